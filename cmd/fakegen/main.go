@@ -0,0 +1,326 @@
+// fakegen writes the test fake of an interface from its method set. It runs
+// through go generate in the package declaring the interface:
+//
+//	//go:generate go run ../../cmd/fakegen -interface Repository -fake Fake -store memory
+//
+// The fake has a Func field per method of the interface. A method runs its
+// field when a test sets it, to return an error or record the call, and the
+// method of the same name on the store otherwise, the in-memory version
+// written by hand. Methods the store does not have panic until their field
+// is set, so a method added to the interface only needs go generate to keep
+// the fake compiling.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.iface, "interface", "", "name of the interface to fake")
+	flag.StringVar(&opts.fake, "fake", "", "name of the fake type to generate")
+	flag.StringVar(&opts.store, "store", "", "name of the type implementing the fake in memory")
+	flag.StringVar(&opts.out, "out", "", "file to write, the lowercased fake name with a _gen.go suffix by default")
+	flag.Parse()
+	if opts.iface == "" || opts.fake == "" || opts.store == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if opts.out == "" {
+		opts.out = strings.ToLower(opts.fake) + "_gen.go"
+	}
+
+	if err := run(".", opts); err != nil {
+		fmt.Fprintf(os.Stderr, "fakegen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+type options struct {
+	iface, fake, store, out string
+}
+
+// run generates the fake of the package in dir.
+func run(dir string, opts options) error {
+	pkg, module, err := load(dir, opts.out)
+	if err != nil {
+		return err
+	}
+	src, err := generate(pkg, module, opts)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, opts.out), src, 0o644)
+}
+
+// listedPackage is the part of the output of go list read by load.
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	Export     string
+	GoFiles    []string
+	DepOnly    bool
+	Module     *struct{ Path string }
+}
+
+// load type checks the package in dir from its sources, leaving out the
+// file previously generated, and its dependencies from their export data.
+// Type errors are ignored: the other files may refer to the fake about to
+// be generated.
+func load(dir, generated string) (*types.Package, string, error) {
+	cmd := exec.Command("go", "list", "-e", "-export", "-deps", "-json", ".")
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, "", fmt.Errorf("go list: %w", err)
+	}
+
+	exports := map[string]string{}
+	var target *listedPackage
+	for dec := json.NewDecoder(bytes.NewReader(out)); ; {
+		var p listedPackage
+		if err := dec.Decode(&p); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, "", fmt.Errorf("go list: %w", err)
+		}
+		exports[p.ImportPath] = p.Export
+		if !p.DepOnly {
+			target = &p
+		}
+	}
+	if target == nil {
+		return nil, "", fmt.Errorf("no package in %s", dir)
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range target.GoFiles {
+		if name == generated {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(target.Dir, name), nil, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		files = append(files, f)
+	}
+	lookup := func(path string) (io.ReadCloser, error) {
+		if exports[path] == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(exports[path])
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "gc", lookup),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(target.ImportPath, fset, files, nil)
+
+	var module string
+	if target.Module != nil {
+		module = target.Module.Path
+	}
+
+	return pkg, module, nil
+}
+
+// method is a method of the fake.
+type method struct {
+	Name string
+	// Params declares the parameters, Args passes them on
+	Params, Args string
+	Results      string
+	Func         string
+	// Stored is true when the store has the method
+	Stored bool
+}
+
+// generate returns the formatted source of the fake of opts.iface in pkg.
+func generate(pkg *types.Package, module string, opts options) ([]byte, error) {
+	obj, ok := pkg.Scope().Lookup(opts.iface).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type %s in %s", opts.iface, pkg.Path())
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", opts.iface)
+	}
+	store, ok := pkg.Scope().Lookup(opts.store).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type %s in %s", opts.store, pkg.Path())
+	}
+	storeMethods := types.NewMethodSet(types.NewPointer(store.Type()))
+
+	imports := map[string]string{}
+	qualifier := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		imports[p.Path()] = p.Name()
+		return p.Name()
+	}
+
+	var methods []method
+	for i := 0; i < iface.NumMethods(); i++ {
+		fn := iface.Method(i)
+		sig := fn.Type().(*types.Signature)
+		m := method{
+			Name:    fn.Name(),
+			Results: results(sig, qualifier),
+		}
+
+		var params, args []string
+		for j := 0; j < sig.Params().Len(); j++ {
+			p := sig.Params().At(j)
+			name := p.Name()
+			if name == "" || name == "_" || name == "f" {
+				name = fmt.Sprintf("p%d", j)
+			}
+			typ, arg := types.TypeString(p.Type(), qualifier), name
+			if sig.Variadic() && j == sig.Params().Len()-1 {
+				typ = "..." + types.TypeString(p.Type().(*types.Slice).Elem(), qualifier)
+				arg += "..."
+			}
+			params = append(params, name+" "+typ)
+			args = append(args, arg)
+		}
+		m.Params = strings.Join(params, ", ")
+		m.Args = strings.Join(args, ", ")
+		m.Func = "func(" + m.Params + ")"
+		if m.Results != "" {
+			m.Func += " " + m.Results
+		}
+
+		if sel := storeMethods.Lookup(pkg, fn.Name()); sel != nil {
+			stored := sel.Obj().Type().(*types.Signature)
+			m.Stored = types.Identical(types.NewSignatureType(nil, nil, nil, stored.Params(), stored.Results(), stored.Variadic()), sig)
+		}
+		methods = append(methods, m)
+	}
+
+	var buf bytes.Buffer
+	err := fakeTemplate.Execute(&buf, map[string]interface{}{
+		"Package":   pkg.Name(),
+		"Interface": opts.iface,
+		"Fake":      opts.fake,
+		"Store":     opts.store,
+		"Imports":   importGroups(imports, module),
+		"Methods":   methods,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %w", opts.out, err)
+	}
+
+	return src, nil
+}
+
+// results returns the result list of sig as written after the parameters.
+func results(sig *types.Signature, qualifier types.Qualifier) string {
+	var list []string
+	for i := 0; i < sig.Results().Len(); i++ {
+		list = append(list, types.TypeString(sig.Results().At(i).Type(), qualifier))
+	}
+
+	switch len(list) {
+	case 0:
+		return ""
+	case 1:
+		return list[0]
+	}
+
+	return "(" + strings.Join(list, ", ") + ")"
+}
+
+// importGroups sorts the imports into the standard library, other modules
+// and the packages of module, the order of the imports of this repository.
+// Paths are aliased when their name is not their last element.
+func importGroups(imports map[string]string, module string) [][]string {
+	groups := make([][]string, 3)
+	for path, name := range imports {
+		spec := fmt.Sprintf("%q", path)
+		if filepath.Base(path) != name {
+			spec = name + " " + spec
+		}
+		group := 1
+		switch {
+		case !strings.Contains(strings.Split(path, "/")[0], "."):
+			group = 0
+		case module != "" && (path == module || strings.HasPrefix(path, module+"/")):
+			group = 2
+		}
+		groups[group] = append(groups[group], spec)
+	}
+
+	var nonEmpty [][]string
+	for _, group := range groups {
+		if len(group) > 0 {
+			sort.Strings(group)
+			nonEmpty = append(nonEmpty, group)
+		}
+	}
+
+	return nonEmpty
+}
+
+var fakeTemplate = template.Must(template.New("fake").Parse(`// Code generated by fakegen from {{.Interface}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+{{range .}}
+	{{.}}
+{{- end}}
+{{- end}}
+)
+
+// {{.Fake}} implements {{.Interface}} for tests. Each method runs its Func
+// field when set and the method of {{.Store}} otherwise.
+type {{.Fake}} struct {
+	{{.Store}} *{{.Store}}
+{{range .Methods}}
+	{{.Name}}Func {{.Func}}
+{{- end}}
+}
+{{range .Methods}}
+func (f *{{$.Fake}}) {{.Name}}({{.Params}}) {{.Results}} {
+	if f.{{.Name}}Func != nil {
+		{{if .Results}}return {{end}}f.{{.Name}}Func({{.Args}})
+		{{- if not .Results}}
+		return
+		{{- end}}
+	}
+{{- if .Stored}}
+
+	{{if .Results}}return {{end}}f.{{$.Store}}.{{.Name}}({{.Args}})
+{{- else}}
+
+	panic("{{$.Fake}}.{{.Name}}Func is not set and {{$.Store}} has no {{.Name}}")
+{{- end}}
+}
+{{end}}`))
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+const storeSource = `package store
+
+import (
+	"context"
+	"time"
+)
+
+type Store interface {
+	Get(ctx context.Context, id uint64) (string, error)
+	Touch(ctx context.Context, ids ...uint64)
+	Expire(time.Duration) error
+}
+
+type memory struct{}
+
+func (m *memory) Get(ctx context.Context, id uint64) (string, error) { return "", nil }
+
+// Touch takes a slice, not the variadic of Store
+func (m *memory) Touch(ctx context.Context, ids []uint64) {}
+`
+
+func TestGenerate(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "store.go", storeSource, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("example.com/store", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(pkg, "example.com", options{iface: "Store", fake: "Fake", store: "memory", out: "fake_gen.go"})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, want := range []string{
+		"import (\n\t\"context\"\n\t\"time\"\n)",
+		"\tmemory *memory\n",
+		"\tGetFunc    func(ctx context.Context, id uint64) (string, error)\n",
+		"\tTouchFunc  func(ctx context.Context, ids ...uint64)\n",
+		"\tExpireFunc func(p0 time.Duration) error\n",
+		"\treturn f.memory.Get(ctx, id)\n",
+		"\t\tf.TouchFunc(ctx, ids...)\n\t\treturn\n",
+		`panic("Fake.TouchFunc is not set and memory has no Touch")`,
+		`panic("Fake.ExpireFunc is not set and memory has no Expire")`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated fake lacks %q:\n%s", want, src)
+		}
+	}
+
+	// The fake implements the interface
+	generated, err := parser.ParseFile(fset, "fake_gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("parse the generated fake: %v", err)
+	}
+	assertion, err := parser.ParseFile(fset, "assert.go", "package store\n\nvar _ Store = (*Fake)(nil)\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conf.Check("example.com/store", fset, []*ast.File{file, generated, assertion}, nil); err != nil {
+		t.Errorf("type check the generated fake: %v", err)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	pkg := types.NewPackage("example.com/store", "store")
+	pkg.Scope().Insert(types.NewTypeName(token.NoPos, pkg, "memory", types.NewStruct(nil, nil)))
+
+	for _, opts := range []options{
+		{iface: "Store", fake: "Fake", store: "memory"},
+		{iface: "memory", fake: "Fake", store: "memory"},
+	} {
+		if _, err := generate(pkg, "", opts); err == nil {
+			t.Errorf("generate the fake of %s succeeded, want an error", opts.iface)
+		}
+	}
+}
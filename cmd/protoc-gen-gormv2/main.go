@@ -0,0 +1,270 @@
+// protoc-gen-gormv2 runs the protoc-gen-gorm generator and rewrites its output
+// so the generated Default* helpers and hook interfaces take a gorm.io/gorm
+// (v2) *gorm.DB, the same handle the server opens in server/core_db.go, and
+// protobuf messages are only handled through pointers, as go vet requires
+// of structs holding a mutex. Strict updates also fail when the row they lock
+// does not exist instead of ignoring the error.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/infobloxopen/protoc-gen-gorm/plugin"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// importRewrites maps the v1 packages referenced by generated code to their
+// v2 replacements.
+var importRewrites = map[string]string{
+	"github.com/jinzhu/gorm":                         "gorm.io/gorm",
+	"github.com/infobloxopen/atlas-app-toolkit/gorm": "github.com/sandisuryadi36/micro-svc-template/server/ormutil",
+}
+
+// codeRewrites maps jinzhu/gorm specific calls to their v2 equivalents.
+var codeRewrites = []struct {
+	old, new, imp string
+}{
+	{
+		// The strict update locks the row without checking it exists
+		old: `db.Model(&ormObj).Set("gorm:query_option", "FOR UPDATE").Where("id=?", ormObj.Id).First(lockedRow)`,
+		new: `if err = db.Model(&ormObj).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=?", ormObj.Id).First(lockedRow).Error; err != nil {
+		return nil, err
+	}`,
+		imp: "gorm.io/gorm/clause",
+	},
+	{
+		old: `.Set("gorm:query_option", "FOR UPDATE")`,
+		new: `.Clauses(clause.Locking{Strength: "UPDATE"})`,
+		imp: "gorm.io/gorm/clause",
+	},
+}
+
+func main() {
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "protoc-gen-gormv2: %v\n", err)
+		os.Exit(1)
+	}
+
+	var request pluginpb.CodeGeneratorRequest
+	if err := proto.Unmarshal(input, &request); err != nil {
+		fmt.Fprintf(os.Stderr, "protoc-gen-gormv2: %v\n", err)
+		os.Exit(1)
+	}
+
+	out, err := proto.Marshal(generate(&request))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "protoc-gen-gormv2: %v\n", err)
+		os.Exit(1)
+	}
+
+	os.Stdout.Write(out)
+}
+
+// generate runs protoc-gen-gorm and rewrites its output. Failures are
+// reported to protoc in the Error of the response.
+func generate(request *pluginpb.CodeGeneratorRequest) *pluginpb.CodeGeneratorResponse {
+	fail := func(err error) *pluginpb.CodeGeneratorResponse {
+		return &pluginpb.CodeGeneratorResponse{Error: proto.String(err.Error())}
+	}
+
+	builder, err := plugin.New(protogen.Options{}, request)
+	if err != nil {
+		return fail(err)
+	}
+
+	response, err := builder.Generate()
+	if err != nil {
+		return fail(err)
+	}
+	if response.Error != nil {
+		return response
+	}
+
+	for _, file := range response.File {
+		if !strings.HasSuffix(file.GetName(), ".go") {
+			continue
+		}
+		content, err := rewrite(file.GetName(), file.GetContent())
+		if err != nil {
+			return fail(fmt.Errorf("%s: %w", file.GetName(), err))
+		}
+		file.Content = proto.String(content)
+	}
+
+	return response
+}
+
+// rewrite swaps the v1 imports and calls in a generated file for v2 ones.
+func rewrite(name, content string) (string, error) {
+	var imports []string
+	for _, r := range codeRewrites {
+		if strings.Contains(content, r.old) {
+			content = strings.ReplaceAll(content, r.old, r.new)
+			imports = append(imports, r.imp)
+		}
+	}
+
+	content, err := pointerMessages(name, content)
+	if err != nil {
+		return "", err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, content, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if v2, ok := importRewrites[path]; ok {
+			spec.Path.Value = strconv.Quote(v2)
+		}
+	}
+	for _, path := range imports {
+		addImport(f, path)
+	}
+	ast.SortImports(fset, f)
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func addImport(f *ast.File, path string) {
+	for _, spec := range f.Imports {
+		if spec.Path.Value == strconv.Quote(path) {
+			return
+		}
+	}
+
+	name := path[strings.LastIndex(path, "/")+1:]
+	spec := &ast.ImportSpec{
+		Name: ast.NewIdent(name),
+		Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(path)},
+	}
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			gen.Specs = append(gen.Specs, spec)
+			f.Imports = append(f.Imports, spec)
+			return
+		}
+	}
+}
+
+// edit replaces the bytes [start, end) of a file with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// pointerMessages rewrites the by-value protobuf messages of the v1 output
+// to pointers: ToPB returns *T, locals declared as var v T become v := &T{},
+// and the & and * taken of those values and of ToPB results are dropped.
+func pointerMessages(name, content string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, content, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+
+	// Messages are the types with a generated ToORM method.
+	messages := map[string]bool{}
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == "ToORM" && fn.Recv != nil {
+			if star, ok := fn.Recv.List[0].Type.(*ast.StarExpr); ok {
+				messages[star.X.(*ast.Ident).Name] = true
+			}
+		}
+	}
+
+	var edits []edit
+	deref := func(pos token.Pos) {
+		edits = append(edits, edit{offset(pos), offset(pos) + 1, ""})
+	}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		// Values that are pointers once rewritten
+		pointers := map[*ast.Object]bool{}
+		if fn.Name.Name == "ToPB" && fn.Recv != nil {
+			result := fn.Type.Results.List[0].Type
+			if ident, ok := result.(*ast.Ident); ok && messages[ident.Name] {
+				edits = append(edits, edit{offset(ident.Pos()), offset(ident.Pos()), "*"})
+			}
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				if len(n.Lhs) == 0 || len(n.Rhs) != 1 {
+					break
+				}
+				ident, ok := n.Lhs[0].(*ast.Ident)
+				if !ok || ident.Obj == nil {
+					break
+				}
+				switch rhs := n.Rhs[0].(type) {
+				case *ast.CallExpr:
+					if sel, ok := rhs.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "ToPB" {
+						pointers[ident.Obj] = true
+					}
+				case *ast.CompositeLit:
+					if lit, ok := rhs.Type.(*ast.Ident); ok && messages[lit.Name] && fn.Name.Name == "ToPB" {
+						edits = append(edits, edit{offset(rhs.Pos()), offset(rhs.Pos()), "&"})
+						pointers[ident.Obj] = true
+					}
+				case *ast.StarExpr:
+					if pointers[ident.Obj] {
+						deref(rhs.Star)
+					}
+				}
+			case *ast.DeclStmt:
+				gen := n.Decl.(*ast.GenDecl)
+				if gen.Tok != token.VAR || len(gen.Specs) != 1 {
+					break
+				}
+				spec := gen.Specs[0].(*ast.ValueSpec)
+				typ, ok := spec.Type.(*ast.Ident)
+				if !ok || !messages[typ.Name] || len(spec.Names) != 1 || len(spec.Values) != 0 {
+					break
+				}
+				pointers[spec.Names[0].Obj] = true
+				edits = append(edits, edit{offset(n.Pos()), offset(n.End()),
+					fmt.Sprintf("%s := &%s{}", spec.Names[0].Name, typ.Name)})
+			case *ast.UnaryExpr:
+				if ident, ok := n.X.(*ast.Ident); ok && n.Op == token.AND && pointers[ident.Obj] {
+					deref(n.OpPos)
+				}
+			}
+			return true
+		})
+	}
+
+	// Apply from the end so earlier offsets stay valid
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, e := range edits {
+		content = content[:e.start] + e.text + content[e.end:]
+	}
+
+	return content, nil
+}
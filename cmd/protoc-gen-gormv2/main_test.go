@@ -0,0 +1,192 @@
+package main
+
+import (
+	"go/format"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// header declares Example as a message, the types with a ToORM method.
+const header = `package pb
+
+func (m *Example) ToORM(ctx context.Context) (ExampleORM, error) {
+	return ExampleORM{}, nil
+}
+`
+
+func TestPointerMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		in, want string
+	}{
+		{
+			name: "ToPB returns a pointer",
+			in: `func (m *ExampleORM) ToPB(ctx context.Context) (Example, error) {
+	to := Example{}
+	var err error
+	return to, err
+}`,
+			want: `func (m *ExampleORM) ToPB(ctx context.Context) (*Example, error) {
+	to := &Example{}
+	var err error
+	return to, err
+}`,
+		},
+		{
+			name: "ToPB result is not referenced",
+			in: `func DefaultReadExample(ctx context.Context, in *Example) (*Example, error) {
+	ormResponse := ExampleORM{}
+	pbResponse, err := ormResponse.ToPB(ctx)
+	return &pbResponse, err
+}`,
+			want: `func DefaultReadExample(ctx context.Context, in *Example) (*Example, error) {
+	ormResponse := ExampleORM{}
+	pbResponse, err := ormResponse.ToPB(ctx)
+	return pbResponse, err
+}`,
+		},
+		{
+			name: "message variable becomes a pointer",
+			in: `func DefaultPatchExample(ctx context.Context, in *Example) (*Example, error) {
+	var pbObj Example
+	pbReadRes, err := DefaultReadExample(ctx, &Example{Id: in.GetId()})
+	if err != nil {
+		return nil, err
+	}
+	pbObj = *pbReadRes
+	if _, err := DefaultApplyFieldMaskExample(ctx, &pbObj, in); err != nil {
+		return nil, err
+	}
+	return &pbObj, nil
+}`,
+			want: `func DefaultPatchExample(ctx context.Context, in *Example) (*Example, error) {
+	pbObj := &Example{}
+	pbReadRes, err := DefaultReadExample(ctx, &Example{Id: in.GetId()})
+	if err != nil {
+		return nil, err
+	}
+	pbObj = pbReadRes
+	if _, err := DefaultApplyFieldMaskExample(ctx, pbObj, in); err != nil {
+		return nil, err
+	}
+	return pbObj, nil
+}`,
+		},
+		{
+			name: "list appends the converted pointers",
+			in: `func DefaultListExample(ctx context.Context, ormResponse []ExampleORM) ([]*Example, error) {
+	pbResponse := []*Example{}
+	for _, responseEntry := range ormResponse {
+		temp, err := responseEntry.ToPB(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pbResponse = append(pbResponse, &temp)
+	}
+	return pbResponse, nil
+}`,
+			want: `func DefaultListExample(ctx context.Context, ormResponse []ExampleORM) ([]*Example, error) {
+	pbResponse := []*Example{}
+	for _, responseEntry := range ormResponse {
+		temp, err := responseEntry.ToPB(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pbResponse = append(pbResponse, temp)
+	}
+	return pbResponse, nil
+}`,
+		},
+		{
+			name: "other types are left alone",
+			in: `func (m *OtherORM) ToPB(ctx context.Context) (Other, error) {
+	to := Other{}
+	var orm ExampleORM
+	return to, use(&orm)
+}`,
+			want: `func (m *OtherORM) ToPB(ctx context.Context) (Other, error) {
+	to := Other{}
+	var orm ExampleORM
+	return to, use(&orm)
+}`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := pointerMessages("test.go", header+tt.in)
+		if err != nil {
+			t.Errorf("%s: pointerMessages: %v", tt.name, err)
+			continue
+		}
+		if got, want := mustFormat(t, got), mustFormat(t, header+tt.want); got != want {
+			t.Errorf("%s: pointerMessages =\n%s\nwant\n%s", tt.name, got, want)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	in := `package pb
+
+import (
+	gorm1 "github.com/jinzhu/gorm"
+	gorm2 "github.com/infobloxopen/atlas-app-toolkit/gorm"
+)
+
+func DefaultStrictUpdateExample(ctx context.Context, in *Example, db *gorm1.DB) (*Example, error) {
+	ormObj, err := in.ToORM(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lockedRow := &ExampleORM{}
+	db.Model(&ormObj).Set("gorm:query_option", "FOR UPDATE").Where("id=?", ormObj.Id).First(lockedRow)
+	return nil, gorm2.Unused
+}
+`
+	got, err := rewrite("test.go", in)
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	for _, want := range []string{
+		`gorm1 "gorm.io/gorm"`,
+		`gorm2 "github.com/sandisuryadi36/micro-svc-template/server/ormutil"`,
+		`clause "gorm.io/gorm/clause"`,
+		`if err = db.Model(&ormObj).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=?", ormObj.Id).First(lockedRow).Error; err != nil {`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rewrite output lacks %s:\n%s", want, got)
+		}
+	}
+	for _, old := range []string{"jinzhu", "atlas-app-toolkit", "gorm:query_option"} {
+		if strings.Contains(got, old) {
+			t.Errorf("rewrite output still contains %s:\n%s", old, got)
+		}
+	}
+}
+
+func TestGenerateReportsErrors(t *testing.T) {
+	resp := generate(&pluginpb.CodeGeneratorRequest{FileToGenerate: []string{"missing.proto"}})
+	if resp.GetError() == "" {
+		t.Errorf("generate of a missing file = %v, want an error in the response", resp)
+	}
+
+	// Without files to generate there is nothing to do
+	resp = generate(&pluginpb.CodeGeneratorRequest{})
+	if resp.GetError() != "" || len(resp.GetFile()) != 0 {
+		t.Errorf("generate of an empty request = %v, want an empty response", resp)
+	}
+	if _, err := proto.Marshal(resp); err != nil {
+		t.Errorf("Marshal: %v", err)
+	}
+}
+
+func mustFormat(t *testing.T, src string) string {
+	t.Helper()
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		t.Fatalf("format: %v\n%s", err, src)
+	}
+
+	return string(out)
+}
@@ -10,6 +10,10 @@ protoc --proto_path=./proto --proto_path=./proto/libs/ \
 
 protoc --proto_path=./proto --proto_path=./proto/libs/ \
     --go_out=./server/pb --go_opt paths=source_relative \
-    --plugin=$GOPATH/bin/protoc-gen-gorm.exe \
-    --gorm_out=. \
-    ./proto/gorm.proto 
\ No newline at end of file
+    --plugin=$GOPATH/bin/protoc-gen-gormv2.exe \
+    --gormv2_out=. \
+    ./proto/gorm.proto 
+
+protoc --proto_path=./proto --proto_path=./proto/libs/ \
+    --go_out=./server/pb --go_opt paths=source_relative \
+    ./proto/validate.proto
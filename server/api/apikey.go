@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+	"github.com/sandisuryadi36/micro-svc-template/server/auth"
+	"github.com/sandisuryadi36/micro-svc-template/server/db"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+// CreateApiKey POST /api/admin/api-keys
+func (s *Server) CreateApiKey(ctx context.Context, req *pb.CreateApiKeyRequest) (*pb.CreateApiKeyResponse, error) {
+	if strings.TrimSpace(req.GetName()) == "" {
+		return nil, apperr.InvalidArgument("name is required", apperr.Field("name", "is required"))
+	}
+	var expiresAt *time.Time
+	if req.GetExpiresAt() != nil {
+		t := req.GetExpiresAt().AsTime()
+		if !t.After(time.Now()) {
+			return nil, apperr.InvalidArgument("expires_at must be in the future", apperr.Field("expires_at", "must be in the future"))
+		}
+		expiresAt = &t
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, apperr.Internal(ctx, err)
+	}
+	data, err := s.provider.CreateAPIKey(ctx, &pb.ApiKeyORM{
+		Name:      strings.TrimSpace(req.GetName()),
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    strings.Join(strings.Fields(req.GetScopes()), " "),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "API key created", slog.Uint64("id", data.Id), slog.String("prefix", data.Prefix))
+
+	pbObj, err := data.ToPB(ctx)
+	if err != nil {
+		return nil, apperr.Internal(ctx, err)
+	}
+
+	result := &pb.CreateApiKeyResponse{
+		Data: pbObj,
+		Key:  key,
+		HttpStatus: &pb.StandardResponse{
+			Success: true,
+			Code:    http.StatusCreated,
+			Message: "success",
+		},
+	}
+
+	return result, nil
+}
+
+// ListApiKeys GET /api/admin/api-keys
+func (s *Server) ListApiKeys(ctx context.Context, req *pb.ListApiKeysRequest) (*pb.ListApiKeysResponse, error) {
+	// Admins list the keys right after creating or revoking one, which a
+	// lagging replica would not show yet
+	list, err := s.provider.ListAPIKeys(db.WithPrimary(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &pb.ListApiKeysResponse{
+		Data: make([]*pb.ApiKey, 0, len(list)),
+		HttpStatus: &pb.StandardResponse{
+			Success: true,
+			Code:    http.StatusOK,
+			Message: "success",
+		},
+	}
+	for _, data := range list {
+		pbObj, err := data.ToPB(ctx)
+		if err != nil {
+			return nil, apperr.Internal(ctx, err)
+		}
+		result.Data = append(result.Data, pbObj)
+	}
+
+	return result, nil
+}
+
+// RevokeApiKey DELETE /api/admin/api-keys/{id}
+func (s *Server) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyRequest) (*pb.RevokeApiKeyResponse, error) {
+	if err := s.provider.RevokeAPIKey(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "API key revoked", slog.Uint64("id", req.GetId()))
+
+	result := &pb.RevokeApiKeyResponse{
+		HttpStatus: &pb.StandardResponse{
+			Success: true,
+			Code:    http.StatusOK,
+			Message: "success",
+		},
+	}
+
+	return result, nil
+}
@@ -2,21 +2,192 @@ package api
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
 
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+	"github.com/sandisuryadi36/micro-svc-template/server/db"
 	"github.com/sandisuryadi36/micro-svc-template/server/pb"
 )
 
 // Hello /api/hello
-func (s *Server) Hello(ctx context.Context, req *pb.Empty)(*pb.HelloResponse, error) {
+func (s *Server) Hello(ctx context.Context, req *pb.Empty) (*pb.HelloResponse, error) {
 	result := &pb.HelloResponse{
 		Message: "Hello, welcome to micro service template by SandiSuryadi36",
 		HttpStatus: &pb.StandardResponse{
 			Success: true,
-			Code: http.StatusOK,
+			Code:    http.StatusOK,
 			Message: "success",
 		},
 	}
 
 	return result, nil
-}
\ No newline at end of file
+}
+
+// CreateExample POST /api/examples
+func (s *Server) CreateExample(ctx context.Context, req *pb.CreateExampleRequest) (*pb.ExampleResponse, error) {
+	ormObj, err := req.GetData().ToORM(ctx)
+	if err != nil {
+		return nil, apperr.Internal(ctx, err)
+	}
+	ormObj.Id = 0
+	ormObj.DeletedAt = nil
+
+	var data *pb.ExampleORM
+	err = s.provider.WithTx(ctx, func(ctx context.Context) error {
+		// A retried transaction starts again from the request data
+		row := ormObj
+		var err error
+		data, err = s.provider.CreateData(ctx, &row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Example created", slog.Uint64("id", data.Id))
+
+	return exampleResponse(ctx, data, http.StatusCreated)
+}
+
+// GetExample GET /api/examples/{id}
+func (s *Server) GetExample(ctx context.Context, req *pb.GetExampleRequest) (*pb.ExampleResponse, error) {
+	data, err := s.provider.GetData(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return exampleResponse(ctx, data, http.StatusOK)
+}
+
+// ListExamples GET /api/examples
+func (s *Server) ListExamples(ctx context.Context, req *pb.ListExamplesRequest) (*pb.ListExamplesResponse, error) {
+	list, page, err := s.provider.ListData(ctx, db.ListOptions{
+		PageSize:     req.GetPageSize(),
+		PageToken:    req.GetPageToken(),
+		Filter:       req.GetFilter(),
+		OrderBy:      req.GetOrderBy(),
+		IncludeTotal: req.GetIncludeTotal(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &pb.ListExamplesResponse{
+		Data: make([]*pb.Example, 0, len(list)),
+		HttpStatus: &pb.StandardResponse{
+			Success: true,
+			Code:    http.StatusOK,
+			Message: "success",
+		},
+		NextPageToken: page.NextPageToken,
+		TotalSize:     page.TotalSize,
+	}
+	for _, data := range list {
+		pbObj, err := data.ToPB(ctx)
+		if err != nil {
+			return nil, apperr.Internal(ctx, err)
+		}
+		result.Data = append(result.Data, pbObj)
+	}
+
+	return result, nil
+}
+
+// UpdateExample PUT /api/examples/{id}
+func (s *Server) UpdateExample(ctx context.Context, req *pb.UpdateExampleRequest) (*pb.ExampleResponse, error) {
+	return s.updateExample(ctx, req.GetId(), func(data *pb.ExampleORM) error {
+		data.Name = req.GetData().GetName()
+		data.Description = req.GetData().GetDescription()
+		return nil
+	})
+}
+
+// PatchExample PATCH /api/examples/{id}
+func (s *Server) PatchExample(ctx context.Context, req *pb.PatchExampleRequest) (*pb.ExampleResponse, error) {
+	if !req.GetUpdateMask().IsValid(req.GetData()) {
+		return nil, apperr.InvalidArgument("update_mask contains unknown fields", apperr.Field("update_mask", "contains unknown fields"))
+	}
+	if len(req.GetUpdateMask().GetPaths()) == 0 {
+		return nil, apperr.InvalidArgument("update_mask is required", apperr.Field("update_mask", "must name the fields to update"))
+	}
+
+	return s.updateExample(ctx, req.GetId(), func(data *pb.ExampleORM) error {
+		for _, path := range req.GetUpdateMask().GetPaths() {
+			switch path {
+			case "name":
+				data.Name = req.GetData().GetName()
+			case "description":
+				data.Description = req.GetData().GetDescription()
+			default:
+				return apperr.InvalidArgument("update_mask contains fields that cannot be updated",
+					apperr.Field("update_mask", fmt.Sprintf("%q cannot be updated", path)))
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteExample DELETE /api/examples/{id}
+func (s *Server) DeleteExample(ctx context.Context, req *pb.DeleteExampleRequest) (*pb.DeleteExampleResponse, error) {
+	err := s.provider.WithTx(ctx, func(ctx context.Context) error {
+		return s.provider.DeleteData(ctx, req.GetId())
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Example deleted", slog.Uint64("id", req.GetId()))
+
+	result := &pb.DeleteExampleResponse{
+		HttpStatus: &pb.StandardResponse{
+			Success: true,
+			Code:    http.StatusOK,
+			Message: "success",
+		},
+	}
+
+	return result, nil
+}
+
+// updateExample loads and locks the row with the given id, lets apply modify
+// it and saves it back in a single transaction, so concurrent updates of the
+// same example apply one after the other.
+func (s *Server) updateExample(ctx context.Context, id uint64, apply func(*pb.ExampleORM) error) (*pb.ExampleResponse, error) {
+	var data *pb.ExampleORM
+	err := s.provider.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		data, err = s.provider.GetDataForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := apply(data); err != nil {
+			return err
+		}
+		data, err = s.provider.UpdateData(ctx, data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Example updated", slog.Uint64("id", data.Id))
+
+	return exampleResponse(ctx, data, http.StatusOK)
+}
+
+func exampleResponse(ctx context.Context, data *pb.ExampleORM, code uint64) (*pb.ExampleResponse, error) {
+	pbObj, err := data.ToPB(ctx)
+	if err != nil {
+		return nil, apperr.Internal(ctx, err)
+	}
+
+	result := &pb.ExampleResponse{
+		Data: pbObj,
+		HttpStatus: &pb.StandardResponse{
+			Success: true,
+			Code:    code,
+			Message: "success",
+		},
+	}
+
+	return result, nil
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/config"
+	"github.com/sandisuryadi36/micro-svc-template/server/db"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+func newServer(t *testing.T) (*Server, *db.Fake) {
+	t.Helper()
+	repo := db.NewFake()
+	return New(&config.Config{}, repo), repo
+}
+
+func createExample(t *testing.T, s *Server, name string) *pb.Example {
+	t.Helper()
+	resp, err := s.CreateExample(context.Background(), &pb.CreateExampleRequest{
+		Data: &pb.Example{Name: name, Description: name + " description"},
+	})
+	if err != nil {
+		t.Fatalf("CreateExample(%q): %v", name, err)
+	}
+
+	return resp.GetData()
+}
+
+func TestCreateAndGetExample(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newServer(t)
+	// API keys have their own id sequence
+	if _, err := repo.CreateAPIKey(ctx, &pb.ApiKeyORM{Name: "key", KeyHash: "hash"}); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	created, err := s.CreateExample(ctx, &pb.CreateExampleRequest{
+		Data: &pb.Example{Id: 42, Name: "a", Description: "first"},
+	})
+	if err != nil {
+		t.Fatalf("CreateExample: %v", err)
+	}
+	if got := created.GetHttpStatus().GetCode(); got != 201 {
+		t.Errorf("CreateExample code = %d, want 201", got)
+	}
+	if created.GetData().GetId() != 1 {
+		t.Errorf("CreateExample id = %d, want 1, the id of the request is ignored", created.GetData().GetId())
+	}
+	if created.GetData().GetCratedAt() == nil || created.GetData().GetUpdatedAt() == nil {
+		t.Errorf("CreateExample timestamps not set: %v", created.GetData())
+	}
+
+	got, err := s.GetExample(ctx, &pb.GetExampleRequest{Id: created.GetData().GetId()})
+	if err != nil {
+		t.Fatalf("GetExample: %v", err)
+	}
+	if got.GetData().GetName() != "a" || got.GetData().GetDescription() != "first" {
+		t.Errorf("GetExample = %v, want name a and description first", got.GetData())
+	}
+}
+
+func TestExampleNotFound(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newServer(t)
+	deleted := createExample(t, s, "deleted")
+	if _, err := s.DeleteExample(ctx, &pb.DeleteExampleRequest{Id: deleted.GetId()}); err != nil {
+		t.Fatalf("DeleteExample: %v", err)
+	}
+
+	for _, id := range []uint64{deleted.GetId(), 99} {
+		calls := map[string]func() error{
+			"GetExample": func() error {
+				_, err := s.GetExample(ctx, &pb.GetExampleRequest{Id: id})
+				return err
+			},
+			"UpdateExample": func() error {
+				_, err := s.UpdateExample(ctx, &pb.UpdateExampleRequest{Id: id, Data: &pb.Example{Name: "b"}})
+				return err
+			},
+			"DeleteExample": func() error {
+				_, err := s.DeleteExample(ctx, &pb.DeleteExampleRequest{Id: id})
+				return err
+			},
+		}
+		for name, call := range calls {
+			if code := status.Code(call()); code != codes.NotFound {
+				t.Errorf("%s(%d) code = %v, want NotFound", name, id, code)
+			}
+		}
+	}
+}
+
+func TestListExamplesPages(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newServer(t)
+	for _, name := range []string{"c", "a", "e", "b", "d"} {
+		createExample(t, s, name)
+	}
+
+	tests := []struct {
+		orderBy string
+		want    []string
+	}{
+		{orderBy: "", want: []string{"c", "a", "e", "b", "d"}},
+		{orderBy: "name", want: []string{"a", "b", "c", "d", "e"}},
+		{orderBy: "name desc", want: []string{"e", "d", "c", "b", "a"}},
+	}
+	for _, tt := range tests {
+		req := &pb.ListExamplesRequest{PageSize: 2, OrderBy: tt.orderBy, IncludeTotal: true}
+		var names []string
+		for pages := 1; ; pages++ {
+			resp, err := s.ListExamples(ctx, req)
+			if err != nil {
+				t.Fatalf("ListExamples(%q) page %d: %v", tt.orderBy, pages, err)
+			}
+			if resp.GetTotalSize() != 5 {
+				t.Errorf("ListExamples(%q) total_size = %d, want 5", tt.orderBy, resp.GetTotalSize())
+			}
+			for _, data := range resp.GetData() {
+				names = append(names, data.GetName())
+			}
+			if resp.GetNextPageToken() == "" {
+				if pages != 3 {
+					t.Errorf("ListExamples(%q) returned %d pages, want 3", tt.orderBy, pages)
+				}
+				break
+			}
+			req.PageToken = resp.GetNextPageToken()
+		}
+		if !equal(names, tt.want) {
+			t.Errorf("ListExamples(%q) = %v, want %v", tt.orderBy, names, tt.want)
+		}
+	}
+}
+
+func TestListExamplesInvalid(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newServer(t)
+	for _, name := range []string{"a", "b", "c"} {
+		createExample(t, s, name)
+	}
+	first, err := s.ListExamples(ctx, &pb.ListExamplesRequest{PageSize: 1, OrderBy: "name"})
+	if err != nil {
+		t.Fatalf("ListExamples: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  *pb.ListExamplesRequest
+	}{
+		{name: "token of another order", req: &pb.ListExamplesRequest{OrderBy: "name desc", PageToken: first.GetNextPageToken()}},
+		{name: "malformed token", req: &pb.ListExamplesRequest{PageToken: "not a token"}},
+		{name: "unknown sort field", req: &pb.ListExamplesRequest{OrderBy: "deletedAt"}},
+		{name: "unknown filter field", req: &pb.ListExamplesRequest{Filter: "deletedAt == null"}},
+	}
+	for _, tt := range tests {
+		_, err := s.ListExamples(ctx, tt.req)
+		if code := status.Code(err); code != codes.InvalidArgument {
+			t.Errorf("%s: code = %v, want InvalidArgument", tt.name, code)
+		}
+	}
+}
+
+func TestFailedTransactionRollsBack(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newServer(t)
+	kept := createExample(t, s, "kept")
+
+	errFail := errors.New("fail")
+	err := repo.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := repo.CreateData(ctx, &pb.ExampleORM{Name: "created"}); err != nil {
+			return err
+		}
+		if err := repo.DeleteData(ctx, kept.GetId()); err != nil {
+			return err
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("WithTx = %v, want %v", err, errFail)
+	}
+
+	resp, err := s.ListExamples(ctx, &pb.ListExamplesRequest{})
+	if err != nil {
+		t.Fatalf("ListExamples: %v", err)
+	}
+	if len(resp.GetData()) != 1 || resp.GetData()[0].GetName() != "kept" {
+		t.Errorf("ListExamples after rollback = %v, want only kept", resp.GetData())
+	}
+	if next := createExample(t, s, "next"); next.GetId() != kept.GetId()+1 {
+		t.Errorf("id after rollback = %d, want %d", next.GetId(), kept.GetId()+1)
+	}
+}
+
+func TestPatchExample(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newServer(t)
+	data := createExample(t, s, "a")
+
+	_, err := s.PatchExample(ctx, &pb.PatchExampleRequest{
+		Id:         data.GetId(),
+		Data:       &pb.Example{Name: "b", Description: "ignored"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	if err != nil {
+		t.Fatalf("PatchExample: %v", err)
+	}
+	_, err = s.PatchExample(ctx, &pb.PatchExampleRequest{
+		Id:         data.GetId(),
+		Data:       &pb.Example{Name: "c", Id: 7},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "id"}},
+	})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("PatchExample of id code = %v, want InvalidArgument", code)
+	}
+
+	got, err := s.GetExample(ctx, &pb.GetExampleRequest{Id: data.GetId()})
+	if err != nil {
+		t.Fatalf("GetExample: %v", err)
+	}
+	if got.GetData().GetName() != "b" || got.GetData().GetDescription() != data.GetDescription() {
+		t.Errorf("GetExample after patches = %v, want name b and the description unchanged", got.GetData())
+	}
+}
+
+func TestPatchExampleRequiresMask(t *testing.T) {
+	for _, mask := range []*fieldmaskpb.FieldMask{nil, {}} {
+		_, err := (&Server{}).PatchExample(context.Background(), &pb.PatchExampleRequest{
+			Id:         1,
+			Data:       &pb.Example{Name: "b"},
+			UpdateMask: mask,
+		})
+		if code := status.Code(err); code != codes.InvalidArgument {
+			t.Errorf("PatchExample with update_mask %v code = %v, want InvalidArgument", mask, code)
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
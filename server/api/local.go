@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Local serves the in-process gateway (pb.RegisterApiServiceHandlerServer),
+// which calls the server methods directly instead of going through a gRPC
+// connection. Every call still runs through the gRPC interceptors, so
+// authentication, logging and the other middlewares apply as they do over
+// the network.
+//
+// Every RPC of ApiService needs a method here; a missing one answers
+// codes.Unimplemented rather than bypassing the interceptors.
+type Local struct {
+	pb.UnimplementedApiServiceServer
+	srv         pb.ApiServiceServer
+	interceptor grpc.UnaryServerInterceptor
+}
+
+// NewLocal wraps srv with interceptor, usually the chain given to the gRPC
+// server.
+func NewLocal(srv pb.ApiServiceServer, interceptor grpc.UnaryServerInterceptor) *Local {
+	return &Local{srv: srv, interceptor: interceptor}
+}
+
+// invoke runs method through the generated handler of the service
+// descriptor, which builds the grpc.UnaryServerInfo and calls the
+// interceptor.
+func invoke[Resp proto.Message](l *Local, ctx context.Context, method string, req proto.Message) (Resp, error) {
+	var zero Resp
+	for _, desc := range pb.ApiService_ServiceDesc.Methods {
+		if desc.MethodName != method {
+			continue
+		}
+		dec := func(in interface{}) error {
+			proto.Merge(in.(proto.Message), req)
+			return nil
+		}
+		resp, err := desc.Handler(l.srv, ctx, dec, l.interceptor)
+		if err != nil {
+			return zero, err
+		}
+		return resp.(Resp), nil
+	}
+
+	return zero, status.Errorf(codes.Unimplemented, "method %s not implemented", method)
+}
+
+func (l *Local) Hello(ctx context.Context, req *pb.Empty) (*pb.HelloResponse, error) {
+	return invoke[*pb.HelloResponse](l, ctx, "Hello", req)
+}
+
+func (l *Local) CreateExample(ctx context.Context, req *pb.CreateExampleRequest) (*pb.ExampleResponse, error) {
+	return invoke[*pb.ExampleResponse](l, ctx, "CreateExample", req)
+}
+
+func (l *Local) GetExample(ctx context.Context, req *pb.GetExampleRequest) (*pb.ExampleResponse, error) {
+	return invoke[*pb.ExampleResponse](l, ctx, "GetExample", req)
+}
+
+func (l *Local) ListExamples(ctx context.Context, req *pb.ListExamplesRequest) (*pb.ListExamplesResponse, error) {
+	return invoke[*pb.ListExamplesResponse](l, ctx, "ListExamples", req)
+}
+
+func (l *Local) UpdateExample(ctx context.Context, req *pb.UpdateExampleRequest) (*pb.ExampleResponse, error) {
+	return invoke[*pb.ExampleResponse](l, ctx, "UpdateExample", req)
+}
+
+func (l *Local) PatchExample(ctx context.Context, req *pb.PatchExampleRequest) (*pb.ExampleResponse, error) {
+	return invoke[*pb.ExampleResponse](l, ctx, "PatchExample", req)
+}
+
+func (l *Local) DeleteExample(ctx context.Context, req *pb.DeleteExampleRequest) (*pb.DeleteExampleResponse, error) {
+	return invoke[*pb.DeleteExampleResponse](l, ctx, "DeleteExample", req)
+}
+
+func (l *Local) CreateApiKey(ctx context.Context, req *pb.CreateApiKeyRequest) (*pb.CreateApiKeyResponse, error) {
+	return invoke[*pb.CreateApiKeyResponse](l, ctx, "CreateApiKey", req)
+}
+
+func (l *Local) ListApiKeys(ctx context.Context, req *pb.ListApiKeysRequest) (*pb.ListApiKeysResponse, error) {
+	return invoke[*pb.ListApiKeysResponse](l, ctx, "ListApiKeys", req)
+}
+
+func (l *Local) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyRequest) (*pb.RevokeApiKeyResponse, error) {
+	return invoke[*pb.RevokeApiKeyResponse](l, ctx, "RevokeApiKey", req)
+}
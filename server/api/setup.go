@@ -1,23 +1,24 @@
 package api
 
 import (
-
+	"github.com/sandisuryadi36/micro-svc-template/server/config"
 	"github.com/sandisuryadi36/micro-svc-template/server/db"
 	"github.com/sandisuryadi36/micro-svc-template/server/pb"
-
-	"gorm.io/gorm"
 )
 
 // Server setup
 type Server struct {
-	provider *db.GormProvider
+	config   *config.Config
+	provider db.Repository
 	pb.ApiServiceServer
 }
 
-// New initiate server
-func New(db01 *gorm.DB) *Server {
+// New initiate server on top of repo, a db.GormProvider or a db.Fake in
+// tests
+func New(cfg *config.Config, repo db.Repository) *Server {
 	return &Server{
-		provider: db.NewProvider(db01),
+		config:           cfg,
+		provider:         repo,
 		ApiServiceServer: nil,
 	}
 }
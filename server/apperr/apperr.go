@@ -0,0 +1,99 @@
+// Package apperr builds the gRPC status errors returned by handlers and
+// providers.
+//
+// Each constructor picks the status code, and through it the HTTP status
+// of the gateway, so handlers do not have to. Invalid fields are attached
+// as errdetails.BadRequest field violations, which the gateway lists in the
+// errors of its StandardResponse. Internal errors are logged and replaced by
+// a generic message so database or library errors never reach the client.
+package apperr
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Violation is an invalid field of a request.
+type Violation = errdetails.BadRequest_FieldViolation
+
+// Field describes the problem of one field, named by its path in the
+// request (data.name).
+func Field(field, description string) *Violation {
+	return &Violation{Field: field, Description: description}
+}
+
+// InvalidArgument is returned for a malformed request, HTTP 400.
+func InvalidArgument(message string, violations ...*Violation) error {
+	return withViolations(codes.InvalidArgument, message, violations)
+}
+
+// NotFound is returned when the requested resource does not exist, HTTP 404.
+func NotFound(format string, args ...interface{}) error {
+	return status.Errorf(codes.NotFound, format, args...)
+}
+
+// Conflict is returned when the resource already exists, such as on a
+// unique constraint, HTTP 409.
+func Conflict(message string, violations ...*Violation) error {
+	return withViolations(codes.AlreadyExists, message, violations)
+}
+
+// FailedPrecondition is returned when the request is valid but the current
+// state does not allow it, HTTP 400.
+func FailedPrecondition(format string, args ...interface{}) error {
+	return status.Errorf(codes.FailedPrecondition, format, args...)
+}
+
+// Aborted is returned when the operation lost a race with another one and
+// may be retried, HTTP 409.
+func Aborted(format string, args ...interface{}) error {
+	return status.Errorf(codes.Aborted, format, args...)
+}
+
+// Unauthenticated is returned when the caller is not identified, HTTP 401.
+func Unauthenticated(format string, args ...interface{}) error {
+	return status.Errorf(codes.Unauthenticated, "Unauthenticated: "+format, args...)
+}
+
+// PermissionDenied is returned when the caller may not make the request,
+// HTTP 403.
+func PermissionDenied(format string, args ...interface{}) error {
+	return status.Errorf(codes.PermissionDenied, "Permission Denied: "+format, args...)
+}
+
+// Internal logs err with the request fields of ctx and returns a
+// codes.Internal error that leaves the details out, HTTP 500.
+func Internal(ctx context.Context, err error) error {
+	slog.ErrorContext(ctx, "Internal error", slog.String("error", err.Error()))
+	return status.Error(codes.Internal, "Internal Error")
+}
+
+// Violations returns the field violations attached to err.
+func Violations(err error) []*Violation {
+	var violations []*Violation
+	for _, detail := range status.Convert(err).Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			violations = append(violations, br.GetFieldViolations()...)
+		}
+	}
+
+	return violations
+}
+
+func withViolations(code codes.Code, message string, violations []*Violation) error {
+	st := status.New(code, message)
+	if len(violations) == 0 {
+		return st.Err()
+	}
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
@@ -0,0 +1,77 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConstructors(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{name: "InvalidArgument", err: InvalidArgument("Invalid request"), code: codes.InvalidArgument, message: "Invalid request"},
+		{name: "NotFound", err: NotFound("Example %d not found", 1), code: codes.NotFound, message: "Example 1 not found"},
+		{name: "Conflict", err: Conflict("Record already exists"), code: codes.AlreadyExists, message: "Record already exists"},
+		{name: "FailedPrecondition", err: FailedPrecondition("Record is still referenced from %s", "owners"), code: codes.FailedPrecondition, message: "Record is still referenced from owners"},
+		{name: "Aborted", err: Aborted("Concurrent update"), code: codes.Aborted, message: "Concurrent update"},
+		{name: "Unauthenticated", err: Unauthenticated("token expired"), code: codes.Unauthenticated, message: "Unauthenticated: token expired"},
+		{name: "PermissionDenied", err: PermissionDenied("missing role %s", "admin"), code: codes.PermissionDenied, message: "Permission Denied: missing role admin"},
+		{name: "Internal", err: Internal(context.Background(), errors.New("pq: connection refused")), code: codes.Internal, message: "Internal Error"},
+	}
+	for _, tt := range tests {
+		st := status.Convert(tt.err)
+		if st.Code() != tt.code || st.Message() != tt.message {
+			t.Errorf("%s = %v %q, want %v %q", tt.name, st.Code(), st.Message(), tt.code, tt.message)
+		}
+	}
+}
+
+func TestViolations(t *testing.T) {
+	violations := []*Violation{
+		Field("data.name", "is required"),
+		Field("data.description", "must be at most 255 characters"),
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want []*Violation
+	}{
+		{name: "invalid argument", err: InvalidArgument("Invalid request", violations...), want: violations},
+		{name: "conflict", err: Conflict("name already exists", violations[0]), want: violations[:1]},
+		{name: "without violations", err: InvalidArgument("Invalid request")},
+		{name: "plain error", err: errors.New("fail")},
+		{name: "nil", err: nil},
+	}
+	for _, tt := range tests {
+		got := Violations(tt.err)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Violations = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].GetField() != tt.want[i].GetField() || got[i].GetDescription() != tt.want[i].GetDescription() {
+				t.Errorf("%s: violation %d = %v, want %v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	// The violations survive the round trip through the status proto sent
+	// over the wire
+	err := status.ErrorProto(status.Convert(InvalidArgument("Invalid request", violations...)).Proto())
+	var fields []string
+	for _, v := range Violations(err) {
+		fields = append(fields, v.GetField())
+	}
+	if want := []string{"data.name", "data.description"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("Violations after the round trip = %v, want %v", fields, want)
+	}
+}
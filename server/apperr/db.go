@@ -0,0 +1,262 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// Postgres error codes, see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgNotNullViolation     = "23502"
+	pgCheckViolation       = "23514"
+	pgStringTooLong        = "22001"
+	pgInvalidText          = "22P02"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// MySQL error numbers, see fromMySQL.
+const (
+	mysqlDupEntry         = 1062
+	mysqlBadNull          = 1048
+	mysqlDeadlock         = 1213
+	mysqlNoReferencedRow  = 1216
+	mysqlRowIsReferenced  = 1217
+	mysqlRowIsReferenced2 = 1451
+	mysqlNoReferencedRow2 = 1452
+	mysqlIncorrectValue   = 1366
+	mysqlDataTooLong      = 1406
+	mysqlTruncatedValue   = 1292
+	mysqlCheckViolated    = 3819
+)
+
+// mysqlKey and mysqlColumn extract the key of a duplicate entry and the
+// column of a null value from MySQL error messages.
+var (
+	mysqlKey    = regexp.MustCompile(`for key '([^']+)'`)
+	mysqlColumn = regexp.MustCompile(`^Column '([^']+)'`)
+)
+
+// keyColumns extracts the columns of a constraint from the detail of a
+// Postgres error: Key (name, owner_id)=(foo, 1) already exists.
+var keyColumns = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// FromDB translates an error of a GORM query on Postgres, SQLite or MySQL
+// into the matching status: record not found, unique and foreign key
+// violations and other integrity errors become client errors, serialization
+// failures and deadlocks become codes.Aborted, and anything else is logged
+// and returned as Internal.
+// Errors that already carry a status are returned unchanged.
+func FromDB(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound("Record not found")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return Conflict("Record already exists")
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return FailedPrecondition("Referenced record does not exist")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "Request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "Request timed out")
+	}
+
+	var (
+		pgErr     *pgconn.PgError
+		sqliteErr sqlite3.Error
+		mysqlErr  *mysql.MySQLError
+	)
+	switch {
+	case errors.As(err, &pgErr):
+		return fromPostgres(ctx, err, pgErr)
+	case errors.As(err, &sqliteErr):
+		return fromSQLite(ctx, err, sqliteErr)
+	case errors.As(err, &mysqlErr):
+		return fromMySQL(ctx, err, mysqlErr)
+	}
+
+	return Internal(ctx, err)
+}
+
+// Retryable reports whether err is a serialization failure, deadlock or
+// lock conflict of any supported database, or has already been translated
+// to codes.Aborted, so that running the transaction again may succeed.
+func Retryable(err error) bool {
+	var (
+		pgErr     *pgconn.PgError
+		sqliteErr sqlite3.Error
+		mysqlErr  *mysql.MySQLError
+	)
+	switch {
+	case errors.As(err, &pgErr):
+		return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+	case errors.As(err, &sqliteErr):
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	case errors.As(err, &mysqlErr):
+		return mysqlErr.Number == mysqlDeadlock
+	}
+
+	return status.Code(err) == codes.Aborted
+}
+
+func fromPostgres(ctx context.Context, err error, pgErr *pgconn.PgError) error {
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return conflict(constraintColumns(pgErr))
+	case pgForeignKeyViolation:
+		if strings.Contains(pgErr.Detail, "still referenced") {
+			return FailedPrecondition("Record is still referenced from %s", pgErr.TableName)
+		}
+		columns := constraintColumns(pgErr)
+		if len(columns) == 0 {
+			return FailedPrecondition("Referenced record does not exist")
+		}
+		return FailedPrecondition("Referenced %s does not exist", strings.Join(columns, ", "))
+	case pgNotNullViolation:
+		return required(pgErr.ColumnName)
+	case pgCheckViolation:
+		return InvalidArgument("Value violates constraint " + pgErr.ConstraintName)
+	case pgStringTooLong:
+		return InvalidArgument("Value too long")
+	case pgInvalidText:
+		return InvalidArgument("Invalid value")
+	case pgSerializationFailure, pgDeadlockDetected:
+		return Aborted("Concurrent update, retry the request")
+	}
+
+	return Internal(ctx, err)
+}
+
+// fromSQLite translates the constraint errors of SQLite, whose message names
+// the columns: UNIQUE constraint failed: api_keys.key_hash.
+func fromSQLite(ctx context.Context, err error, sqliteErr sqlite3.Error) error {
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return conflict(sqliteColumns(sqliteErr))
+	case sqlite3.ErrConstraintForeignKey:
+		return FailedPrecondition("Referenced record does not exist or record is still referenced")
+	case sqlite3.ErrConstraintNotNull:
+		columns := sqliteColumns(sqliteErr)
+		if len(columns) == 1 {
+			return required(columns[0])
+		}
+		return InvalidArgument("Missing required value")
+	case sqlite3.ErrConstraintCheck:
+		return InvalidArgument("Value violates constraint")
+	}
+	if sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked {
+		return Aborted("Concurrent update, retry the request")
+	}
+
+	return Internal(ctx, err)
+}
+
+// fromMySQL translates the MySQL server errors, see
+// https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+func fromMySQL(ctx context.Context, err error, mysqlErr *mysql.MySQLError) error {
+	switch mysqlErr.Number {
+	case mysqlDupEntry:
+		var columns []string
+		if m := mysqlKey.FindStringSubmatch(mysqlErr.Message); m != nil {
+			_, key, _ := strings.Cut(m[1], ".")
+			if key == "" {
+				key = m[1]
+			}
+			if key != "PRIMARY" {
+				columns = []string{key}
+			}
+		}
+		return conflict(columns)
+	case mysqlNoReferencedRow, mysqlNoReferencedRow2:
+		return FailedPrecondition("Referenced record does not exist")
+	case mysqlRowIsReferenced, mysqlRowIsReferenced2:
+		return FailedPrecondition("Record is still referenced")
+	case mysqlBadNull:
+		if m := mysqlColumn.FindStringSubmatch(mysqlErr.Message); m != nil {
+			return required(m[1])
+		}
+		return InvalidArgument("Missing required value")
+	case mysqlCheckViolated:
+		return InvalidArgument("Value violates constraint")
+	case mysqlDataTooLong:
+		return InvalidArgument("Value too long")
+	case mysqlIncorrectValue, mysqlTruncatedValue:
+		return InvalidArgument("Invalid value")
+	case mysqlDeadlock:
+		return Aborted("Concurrent update, retry the request")
+	}
+
+	return Internal(ctx, err)
+}
+
+// conflict reports a unique violation on columns, unknown when empty.
+func conflict(columns []string) error {
+	if len(columns) == 0 {
+		return Conflict("Record already exists")
+	}
+	violations := make([]*Violation, 0, len(columns))
+	for _, column := range columns {
+		violations = append(violations, Field(column, "already exists"))
+	}
+
+	return Conflict(strings.Join(columns, ", ")+" already exists", violations...)
+}
+
+// required reports a not null violation on column.
+func required(column string) error {
+	return InvalidArgument(column+" is required", Field(column, "is required"))
+}
+
+func constraintColumns(pgErr *pgconn.PgError) []string {
+	if pgErr.ColumnName != "" {
+		return []string{pgErr.ColumnName}
+	}
+	m := keyColumns.FindStringSubmatch(pgErr.Detail)
+	if m == nil {
+		return nil
+	}
+
+	columns := strings.Split(m[1], ",")
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+
+	return columns
+}
+
+func sqliteColumns(sqliteErr sqlite3.Error) []string {
+	_, list, ok := strings.Cut(sqliteErr.Error(), "constraint failed: ")
+	if !ok {
+		return nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		column = strings.TrimSpace(column)
+		if _, name, ok := strings.Cut(column, "."); ok {
+			column = name
+		}
+		columns = append(columns, column)
+	}
+
+	return columns
+}
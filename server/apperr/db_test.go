@@ -0,0 +1,208 @@
+package apperr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+func TestFromDB(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+		fields  []string
+	}{
+		{name: "not found", err: gorm.ErrRecordNotFound, code: codes.NotFound, message: "Record not found"},
+		{name: "wrapped not found", err: fmt.Errorf("get: %w", gorm.ErrRecordNotFound), code: codes.NotFound, message: "Record not found"},
+		{name: "translated duplicate", err: gorm.ErrDuplicatedKey, code: codes.AlreadyExists, message: "Record already exists"},
+		{name: "status unchanged", err: NotFound("Example 1 not found"), code: codes.NotFound, message: "Example 1 not found"},
+		{name: "canceled", err: context.Canceled, code: codes.Canceled, message: "Request canceled"},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), code: codes.DeadlineExceeded, message: "Request timed out"},
+		{name: "unknown error", err: errors.New("connection reset by peer"), code: codes.Internal, message: "Internal Error"},
+		{
+			name:    "postgres unique on a column",
+			err:     &pgconn.PgError{Code: "23505", Detail: "Key (key_hash)=(abc) already exists."},
+			code:    codes.AlreadyExists,
+			message: "key_hash already exists",
+			fields:  []string{"key_hash"},
+		},
+		{
+			name:    "postgres unique on columns",
+			err:     &pgconn.PgError{Code: "23505", Detail: "Key (name, owner_id)=(a, 1) already exists."},
+			code:    codes.AlreadyExists,
+			message: "name, owner_id already exists",
+			fields:  []string{"name", "owner_id"},
+		},
+		{name: "postgres unique without detail", err: &pgconn.PgError{Code: "23505"}, code: codes.AlreadyExists, message: "Record already exists"},
+		{
+			name:    "postgres missing reference",
+			err:     &pgconn.PgError{Code: "23503", Detail: `Key (owner_id)=(7) is not present in table "owners".`},
+			code:    codes.FailedPrecondition,
+			message: "Referenced owner_id does not exist",
+		},
+		{
+			name:    "postgres still referenced",
+			err:     &pgconn.PgError{Code: "23503", Detail: `Key (id)=(7) is still referenced from table "examples".`, TableName: "owners"},
+			code:    codes.FailedPrecondition,
+			message: "Record is still referenced from owners",
+		},
+		{
+			name:    "postgres not null",
+			err:     &pgconn.PgError{Code: "23502", ColumnName: "name"},
+			code:    codes.InvalidArgument,
+			message: "name is required",
+			fields:  []string{"name"},
+		},
+		{name: "postgres check", err: &pgconn.PgError{Code: "23514", ConstraintName: "positive_size"}, code: codes.InvalidArgument, message: "Value violates constraint positive_size"},
+		{name: "postgres too long", err: &pgconn.PgError{Code: "22001"}, code: codes.InvalidArgument, message: "Value too long"},
+		{name: "postgres invalid text", err: &pgconn.PgError{Code: "22P02"}, code: codes.InvalidArgument, message: "Invalid value"},
+		{name: "postgres serialization failure", err: &pgconn.PgError{Code: "40001"}, code: codes.Aborted, message: "Concurrent update, retry the request"},
+		{name: "postgres deadlock", err: fmt.Errorf("update: %w", &pgconn.PgError{Code: "40P01"}), code: codes.Aborted, message: "Concurrent update, retry the request"},
+		{name: "postgres other", err: &pgconn.PgError{Code: "53300", Message: "too many connections"}, code: codes.Internal, message: "Internal Error"},
+		{
+			name:    "mysql duplicate entry",
+			err:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'abc' for key 'api_keys.idx_key_hash'"},
+			code:    codes.AlreadyExists,
+			message: "idx_key_hash already exists",
+			fields:  []string{"idx_key_hash"},
+		},
+		{
+			name:    "mysql duplicate primary key",
+			err:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"},
+			code:    codes.AlreadyExists,
+			message: "Record already exists",
+		},
+		{name: "mysql missing reference", err: &mysql.MySQLError{Number: 1452}, code: codes.FailedPrecondition, message: "Referenced record does not exist"},
+		{name: "mysql still referenced", err: &mysql.MySQLError{Number: 1451}, code: codes.FailedPrecondition, message: "Record is still referenced"},
+		{
+			name:    "mysql null",
+			err:     &mysql.MySQLError{Number: 1048, Message: "Column 'name' cannot be null"},
+			code:    codes.InvalidArgument,
+			message: "name is required",
+			fields:  []string{"name"},
+		},
+		{name: "mysql check", err: &mysql.MySQLError{Number: 3819}, code: codes.InvalidArgument, message: "Value violates constraint"},
+		{name: "mysql too long", err: &mysql.MySQLError{Number: 1406}, code: codes.InvalidArgument, message: "Value too long"},
+		{name: "mysql incorrect value", err: &mysql.MySQLError{Number: 1366}, code: codes.InvalidArgument, message: "Invalid value"},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, code: codes.Aborted, message: "Concurrent update, retry the request"},
+		{name: "mysql other", err: &mysql.MySQLError{Number: 1040, Message: "Too many connections"}, code: codes.Internal, message: "Internal Error"},
+		{name: "sqlite busy", err: sqlite3.Error{Code: sqlite3.ErrBusy}, code: codes.Aborted, message: "Concurrent update, retry the request"},
+		{name: "sqlite other", err: sqlite3.Error{Code: sqlite3.ErrIoErr}, code: codes.Internal, message: "Internal Error"},
+	}
+	for _, tt := range tests {
+		err := FromDB(context.Background(), tt.err)
+		st := status.Convert(err)
+		if st.Code() != tt.code || st.Message() != tt.message {
+			t.Errorf("%s: FromDB = %v %q, want %v %q", tt.name, st.Code(), st.Message(), tt.code, tt.message)
+		}
+		var fields []string
+		for _, v := range Violations(err) {
+			fields = append(fields, v.GetField())
+		}
+		if !reflect.DeepEqual(fields, tt.fields) {
+			t.Errorf("%s: violations on %v, want %v", tt.name, fields, tt.fields)
+		}
+	}
+
+	if err := FromDB(context.Background(), nil); err != nil {
+		t.Errorf("FromDB(nil) = %v, want nil", err)
+	}
+}
+
+// TestFromDBSQLite runs the statements against SQLite, as the columns of a
+// constraint are only found in the message of the errors it returns.
+func TestFromDBSQLite(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", ":memory:?_foreign_keys=1")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	defer sqlDB.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE owners (id INTEGER PRIMARY KEY)",
+		`CREATE TABLE widgets (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			owner_id INTEGER REFERENCES owners (id),
+			size INTEGER CHECK (size > 0),
+			UNIQUE (name, owner_id)
+		)`,
+		"INSERT INTO owners (id) VALUES (1)",
+		"INSERT INTO widgets (id, name, owner_id) VALUES (1, 'a', 1)",
+	} {
+		if _, err := sqlDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	tests := []struct {
+		stmt    string
+		code    codes.Code
+		message string
+		fields  []string
+	}{
+		{stmt: "INSERT INTO widgets (id, name) VALUES (1, 'b')", code: codes.AlreadyExists, message: "id already exists", fields: []string{"id"}},
+		{stmt: "INSERT INTO widgets (name, owner_id) VALUES ('a', 1)", code: codes.AlreadyExists, message: "name, owner_id already exists", fields: []string{"name", "owner_id"}},
+		{stmt: "INSERT INTO widgets (owner_id) VALUES (1)", code: codes.InvalidArgument, message: "name is required", fields: []string{"name"}},
+		{stmt: "INSERT INTO widgets (name, size) VALUES ('b', 0)", code: codes.InvalidArgument, message: "Value violates constraint"},
+		{stmt: "INSERT INTO widgets (name, owner_id) VALUES ('b', 2)", code: codes.FailedPrecondition, message: "Referenced record does not exist or record is still referenced"},
+		{stmt: "DELETE FROM owners", code: codes.FailedPrecondition, message: "Referenced record does not exist or record is still referenced"},
+	}
+	for _, tt := range tests {
+		_, err := sqlDB.Exec(tt.stmt)
+		if err == nil {
+			t.Errorf("%s succeeded, want a constraint error", tt.stmt)
+			continue
+		}
+		err = FromDB(context.Background(), err)
+		st := status.Convert(err)
+		if st.Code() != tt.code || st.Message() != tt.message {
+			t.Errorf("%s: FromDB = %v %q, want %v %q", tt.stmt, st.Code(), st.Message(), tt.code, tt.message)
+		}
+		var fields []string
+		for _, v := range Violations(err) {
+			fields = append(fields, v.GetField())
+		}
+		if !reflect.DeepEqual(fields, tt.fields) {
+			t.Errorf("%s: violations on %v, want %v", tt.stmt, fields, tt.fields)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "postgres serialization failure", err: &pgconn.PgError{Code: "40001"}, want: true},
+		{name: "postgres deadlock", err: fmt.Errorf("update: %w", &pgconn.PgError{Code: "40P01"}), want: true},
+		{name: "postgres unique", err: &pgconn.PgError{Code: "23505"}},
+		{name: "sqlite busy", err: sqlite3.Error{Code: sqlite3.ErrBusy}, want: true},
+		{name: "sqlite locked", err: sqlite3.Error{Code: sqlite3.ErrLocked}, want: true},
+		{name: "sqlite constraint", err: sqlite3.Error{Code: sqlite3.ErrConstraint}},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, want: true},
+		{name: "mysql duplicate entry", err: &mysql.MySQLError{Number: 1062}},
+		{name: "aborted status", err: Aborted("Concurrent update"), want: true},
+		{name: "other status", err: Conflict("Record already exists")},
+		{name: "plain error", err: errors.New("fail")},
+		{name: "nil", err: nil},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("%s: Retryable = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package auth verifies the JWT bearer tokens and API keys of incoming RPCs
+// and carries the verified claims in the request context.
+//
+// HS256 tokens are checked against a shared secret and RS256 tokens against
+// the public keys of a JWKS, read from a local file or fetched from a URL.
+// API keys, meant for service-to-service calls, are looked up by hash in
+// the api_keys table and grant the scopes stored with them.
+// Which RPCs need a token is declared per method with the (auth) option in
+// proto/api.proto, see Policy.
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/config"
+)
+
+// ErrNoToken is returned when a request carries no bearer token.
+var ErrNoToken = errors.New("missing bearer token")
+
+// Claims are the verified claims of a token.
+type Claims struct {
+	jwt.RegisteredClaims
+
+	// Roles are checked against the roles of the (auth) option.
+	Roles []string `json:"roles,omitempty"`
+	// Scope (space separated, OAuth 2.0 style) and Permissions are both
+	// checked against the permissions of the (auth) option.
+	Scope       string   `json:"scope,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// HasRole reports whether the caller has role.
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPermission reports whether permission is granted by the scope or the
+// permissions claim.
+func (c *Claims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	for _, s := range strings.Fields(c.Scope) {
+		if s == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying claims.
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims of the authenticated caller, or nil when the
+// request was not authenticated (public method or authentication disabled).
+func FromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(contextKey{}).(*Claims)
+	return claims
+}
+
+// BearerToken returns the token of the "authorization: Bearer <token>"
+// metadata, which the gateway fills from the Authorization header.
+func BearerToken(md metadata.MD) (string, error) {
+	for _, value := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(value, " ")
+		if ok && strings.EqualFold(scheme, "bearer") && strings.TrimSpace(token) != "" {
+			return strings.TrimSpace(token), nil
+		}
+	}
+
+	return "", ErrNoToken
+}
+
+// Verifier checks the signature and the registered claims of tokens, and
+// API keys when a store is configured.
+type Verifier struct {
+	secret  []byte
+	keys    *keySet
+	methods []string
+	options []jwt.ParserOption
+	apiKeys APIKeyStore
+}
+
+// NewVerifier builds a Verifier from the configuration. A JWKS is loaded
+// right away so that a bad source fails at startup. apiKeys is only used
+// when API keys are enabled.
+func NewVerifier(ctx context.Context, cfg config.AuthConfig, apiKeys APIKeyStore) (*Verifier, error) {
+	v := &Verifier{}
+	if cfg.APIKeys {
+		v.apiKeys = apiKeys
+	}
+	if cfg.HMACSecret != "" {
+		v.secret = []byte(cfg.HMACSecret)
+		v.methods = append(v.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKS != "" {
+		v.keys = newKeySet(cfg.JWKS, cfg.JWKSRefresh)
+		if err := v.keys.load(ctx); err != nil {
+			return nil, err
+		}
+		v.methods = append(v.methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(v.methods) == 0 && v.apiKeys == nil {
+		return nil, errors.New("auth: no JWT secret, JWKS or API key store configured")
+	}
+
+	v.options = []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if cfg.Issuer != "" {
+		v.options = append(v.options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		v.options = append(v.options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return v, nil
+}
+
+// Verify parses token and returns its claims if the signature, expiry,
+// issuer and audience are valid.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return v.secret, nil
+		case jwt.SigningMethodRS256.Alg():
+			kid, _ := t.Header["kid"].(string)
+			return v.keys.key(ctx, kid)
+		}
+		return nil, jwt.ErrTokenUnverifiable
+	}, v.options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
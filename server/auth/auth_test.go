@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/config"
+)
+
+var testKey = mustKey()
+
+func mustKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// writeJWKS writes a JWKS holding the public keys by key ID and returns its
+// path.
+func writeJWKS(t *testing.T, keys map[string]*rsa.PublicKey) string {
+	t.Helper()
+	type jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	content, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func TestVerifier(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	v, err := NewVerifier(ctx, config.AuthConfig{
+		HMACSecret:  "secret",
+		JWKS:        writeJWKS(t, map[string]*rsa.PublicKey{"k1": &testKey.PublicKey}),
+		JWKSRefresh: time.Hour,
+		Issuer:      "https://issuer.example",
+		Audience:    "api",
+		Leeway:      30 * time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	claims := func(edit func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub": "user",
+			"iss": "https://issuer.example",
+			"aud": "api",
+			"iat": now.Unix(),
+			"exp": now.Add(time.Minute).Unix(),
+		}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &testKey.PublicKey)})
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "RS256", token: sign(t, jwt.SigningMethodRS256, testKey, "k1", claims(nil)), valid: true},
+		{name: "HS256", token: sign(t, jwt.SigningMethodHS256, []byte("secret"), "", claims(nil)), valid: true},
+		{name: "HS256 with another secret", token: sign(t, jwt.SigningMethodHS256, []byte("other"), "", claims(nil))},
+		{name: "HS256 signed with the RSA public key", token: sign(t, jwt.SigningMethodHS256, publicPEM, "k1", claims(nil))},
+		{name: "RS384", token: sign(t, jwt.SigningMethodRS384, testKey, "k1", claims(nil))},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodRS256, testKey, "k2", claims(nil))},
+		{name: "expired", token: sign(t, jwt.SigningMethodRS256, testKey, "k1", claims(func(c jwt.MapClaims) {
+			c["exp"] = now.Add(-time.Minute).Unix()
+		}))},
+		{name: "expired within leeway", token: sign(t, jwt.SigningMethodRS256, testKey, "k1", claims(func(c jwt.MapClaims) {
+			c["exp"] = now.Add(-10 * time.Second).Unix()
+		})), valid: true},
+		{name: "no exp", token: sign(t, jwt.SigningMethodRS256, testKey, "k1", claims(func(c jwt.MapClaims) {
+			delete(c, "exp")
+		}))},
+		{name: "not yet valid", token: sign(t, jwt.SigningMethodRS256, testKey, "k1", claims(func(c jwt.MapClaims) {
+			c["nbf"] = now.Add(time.Minute).Unix()
+		}))},
+		{name: "issued in the future within leeway", token: sign(t, jwt.SigningMethodRS256, testKey, "k1", claims(func(c jwt.MapClaims) {
+			c["iat"] = now.Add(10 * time.Second).Unix()
+		})), valid: true},
+		{name: "other issuer", token: sign(t, jwt.SigningMethodRS256, testKey, "k1", claims(func(c jwt.MapClaims) {
+			c["iss"] = "https://other.example"
+		}))},
+		{name: "other audience", token: sign(t, jwt.SigningMethodRS256, testKey, "k1", claims(func(c jwt.MapClaims) {
+			c["aud"] = "other"
+		}))},
+		{name: "audience in a list", token: sign(t, jwt.SigningMethodRS256, testKey, "k1", claims(func(c jwt.MapClaims) {
+			c["aud"] = []string{"other", "api"}
+		})), valid: true},
+		{name: "malformed", token: "not.a.token"},
+	}
+	for _, tt := range tests {
+		got, err := v.Verify(ctx, tt.token)
+		if tt.valid && (err != nil || got.Subject != "user") {
+			t.Errorf("%s: Verify = %v, %v, want the claims of user", tt.name, got, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: Verify succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestVerifierWithoutSecretRejectsHS256(t *testing.T) {
+	ctx := context.Background()
+	v, err := NewVerifier(ctx, config.AuthConfig{
+		JWKS:        writeJWKS(t, map[string]*rsa.PublicKey{"k1": &testKey.PublicKey}),
+		JWKSRefresh: time.Hour,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	// Signing with the public key is the classic RS256/HS256 confusion
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &testKey.PublicKey)})
+	for _, secret := range [][]byte{publicPEM, {}} {
+		token := sign(t, jwt.SigningMethodHS256, secret, "k1", jwt.MapClaims{
+			"sub": "user",
+			"iat": time.Now().Unix(),
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+		if _, err := v.Verify(ctx, token); err == nil {
+			t.Errorf("Verify of an HS256 token succeeded without a secret configured")
+		}
+	}
+}
+
+func TestKeySetReloadsUnknownKID(t *testing.T) {
+	ctx := context.Background()
+	other := mustKey()
+	path := writeJWKS(t, map[string]*rsa.PublicKey{"k1": &testKey.PublicKey})
+	keys := newKeySet(path, time.Hour)
+	if err := keys.load(ctx); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	rotated := writeJWKS(t, map[string]*rsa.PublicKey{"k1": &testKey.PublicKey, "k2": &other.PublicKey})
+	content, err := os.ReadFile(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Right after a load, an unknown kid does not reload again
+	if _, err := keys.key(ctx, "k2"); err == nil {
+		t.Fatalf("key(k2) succeeded right after a load")
+	}
+
+	keys.mu.Lock()
+	keys.triedAt = time.Now().Add(-2 * minReload)
+	keys.mu.Unlock()
+	got, err := keys.key(ctx, "k2")
+	if err != nil {
+		t.Fatalf("key(k2) after reload: %v", err)
+	}
+	if got.N.Cmp(other.N) != 0 {
+		t.Errorf("key(k2) returned another key")
+	}
+
+	// A failed reload keeps the previous keys and counts as an attempt
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys.mu.Lock()
+	keys.triedAt = time.Now().Add(-2 * minReload)
+	keys.mu.Unlock()
+	if _, err := keys.key(ctx, "k3"); err == nil || !strings.Contains(err.Error(), "unknown key ID") {
+		t.Errorf("key(k3) = %v, want unknown key ID", err)
+	}
+	if _, err := keys.key(ctx, "k1"); err != nil {
+		t.Errorf("key(k1) after a failed reload: %v", err)
+	}
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+	if time.Since(keys.triedAt) > minReload {
+		t.Errorf("failed reload not recorded as an attempt")
+	}
+	if time.Since(keys.loadedAt) < time.Since(keys.triedAt) {
+		t.Errorf("failed reload recorded as a load")
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	n := base64.RawURLEncoding.EncodeToString(testKey.N.Bytes())
+	tests := []struct {
+		name    string
+		content string
+		kids    []string
+		wantErr bool
+	}{
+		{
+			name:    "RSA signing keys",
+			content: `{"keys":[{"kty":"RSA","kid":"a","use":"sig","n":"` + n + `","e":"AQAB"},{"kty":"RSA","kid":"b","n":"` + n + `","e":"AQAB"}]}`,
+			kids:    []string{"a", "b"},
+		},
+		{
+			name:    "other types and uses are skipped",
+			content: `{"keys":[{"kty":"EC","kid":"ec"},{"kty":"RSA","kid":"enc","use":"enc","n":"` + n + `","e":"AQAB"},{"kty":"RSA","kid":"a","n":"` + n + `","e":"AQAB"}]}`,
+			kids:    []string{"a"},
+		},
+		{name: "no RSA signing key", content: `{"keys":[{"kty":"EC","kid":"ec"}]}`, wantErr: true},
+		{name: "empty", content: `{"keys":[]}`, wantErr: true},
+		{name: "invalid modulus", content: `{"keys":[{"kty":"RSA","kid":"a","n":"!!","e":"AQAB"}]}`, wantErr: true},
+		{name: "invalid exponent", content: `{"keys":[{"kty":"RSA","kid":"a","n":"` + n + `","e":"!!"}]}`, wantErr: true},
+		{name: "exponent too small", content: `{"keys":[{"kty":"RSA","kid":"a","n":"` + n + `","e":"AQ"}]}`, wantErr: true},
+		{name: "exponent too large", content: `{"keys":[{"kty":"RSA","kid":"a","n":"` + n + `","e":"AQAAAAAB"}]}`, wantErr: true},
+		{name: "malformed", content: `{"keys":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		keys, err := parseJWKS([]byte(tt.content))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: parseJWKS succeeded, want an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: parseJWKS: %v", tt.name, err)
+			continue
+		}
+		if len(keys) != len(tt.kids) {
+			t.Errorf("%s: parseJWKS returned %d keys, want %v", tt.name, len(keys), tt.kids)
+		}
+		for _, kid := range tt.kids {
+			if key := keys[kid]; key == nil || key.N.Cmp(testKey.N) != 0 || key.E != 65537 {
+				t.Errorf("%s: key %q = %v, want the test key", tt.name, kid, key)
+			}
+		}
+	}
+}
+
+func mustMarshalPKIX(t *testing.T, key *rsa.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+}
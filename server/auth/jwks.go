@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// minReload limits how often the keys are reloaded, for an unknown key ID
+// or after a failure, so a flood of forged tokens cannot hammer the JWKS
+// endpoint.
+const minReload = 10 * time.Second
+
+// keySet holds the RSA public keys of a JWKS by key ID and reloads them
+// every refresh interval, or earlier when a token names an unknown key.
+// Keys are fetched without holding the lock, one fetch at a time, so token
+// checks with known keys never wait for the source.
+type keySet struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+	group   singleflight.Group
+
+	mu   sync.RWMutex
+	keys map[string]*rsa.PublicKey
+	// loadedAt is the time of the last successful load, triedAt of the
+	// last attempt.
+	loadedAt time.Time
+	triedAt  time.Time
+}
+
+func newKeySet(source string, refresh time.Duration) *keySet {
+	return &keySet{
+		source:  source,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// key returns the key with ID kid. An empty kid is accepted when the set
+// holds a single key. Expired keys are still used while they are reloaded
+// in the background, an unknown kid waits for the reload.
+func (s *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.RLock()
+	key, ok := s.lookup(kid)
+	stale := time.Since(s.loadedAt) > s.refresh
+	due := time.Since(s.triedAt) > minReload
+	s.mu.RUnlock()
+
+	switch {
+	case ok && stale && due:
+		s.reload(ctx)
+		return key, nil
+	case ok:
+		return key, nil
+	case !due:
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+
+	select {
+	case <-s.reload(ctx):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok = s.lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+
+	return key, nil
+}
+
+// reload starts loading the keys unless a load is running already, and
+// returns a channel closed when it is done. Failures are logged and the
+// previous keys are kept.
+func (s *keySet) reload(ctx context.Context) <-chan struct{} {
+	ctx = context.WithoutCancel(ctx)
+	results := s.group.DoChan("load", func() (interface{}, error) {
+		if err := s.load(ctx); err != nil {
+			slog.WarnContext(ctx, "Failed to reload JWKS", slog.String("source", s.source), slog.String("error", err.Error()))
+		}
+		return nil, nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		<-results
+		close(done)
+	}()
+
+	return done
+}
+
+func (s *keySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+
+	return key, ok
+}
+
+// load reads and parses the keys, then swaps them in.
+func (s *keySet) load(ctx context.Context) error {
+	s.mu.Lock()
+	s.triedAt = time.Now()
+	s.mu.Unlock()
+
+	content, err := s.read(ctx)
+	if err != nil {
+		return fmt.Errorf("read JWKS %s: %w", s.source, err)
+	}
+	keys, err := parseJWKS(content)
+	if err != nil {
+		return fmt.Errorf("parse JWKS %s: %w", s.source, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.loadedAt = time.Now()
+
+	return nil
+}
+
+func (s *keySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(s.source, "http://") && !strings.HasPrefix(s.source, "https://") {
+		return os.ReadFile(s.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// parseJWKS returns the RSA signing keys of a JSON Web Key Set by key ID.
+// Keys of other types or uses are skipped.
+func parseJWKS(content []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: invalid modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: invalid exponent: %w", k.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("key %q: unsupported exponent", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no RSA signing keys")
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+// publicServices are infrastructure services callable without a token, so
+// orchestrators can probe the gRPC health of the process.
+var publicServices = map[string]bool{
+	"grpc.health.v1.Health": true,
+}
+
+// policies caches the policy of each full method name.
+var policies sync.Map
+
+// Policy returns the (auth) option of the RPC named fullMethod, in the
+// "/package.Service/Method" form of grpc.UnaryServerInfo. Methods without
+// the option get an empty policy, which requires authentication.
+func Policy(fullMethod string) *pb.MethodAuth {
+	if policy, ok := policies.Load(fullMethod); ok {
+		return policy.(*pb.MethodAuth)
+	}
+
+	policy := lookupPolicy(fullMethod)
+	policies.Store(fullMethod, policy)
+
+	return policy
+}
+
+func lookupPolicy(fullMethod string) *pb.MethodAuth {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if publicServices[service] {
+		return &pb.MethodAuth{Public: true}
+	}
+
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service + "." + method))
+	if err != nil {
+		return &pb.MethodAuth{}
+	}
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return &pb.MethodAuth{}
+	}
+	policy, ok := proto.GetExtension(md.Options(), pb.E_Auth).(*pb.MethodAuth)
+	if !ok || policy == nil {
+		return &pb.MethodAuth{}
+	}
+
+	return policy
+}
+
+// Authorize checks claims against the roles and permissions of policy. The
+// caller needs any one of the roles and every permission.
+func Authorize(policy *pb.MethodAuth, claims *Claims) error {
+	if roles := policy.GetRoles(); len(roles) > 0 {
+		granted := false
+		for _, role := range roles {
+			if claims.HasRole(role) {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return fmt.Errorf("requires one of the roles %s", strings.Join(roles, ", "))
+		}
+	}
+
+	var missing []string
+	for _, permission := range policy.GetPermissions() {
+		if !claims.HasPermission(permission) {
+			missing = append(missing, permission)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing permissions %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *pb.MethodAuth
+		claims *Claims
+		ok     bool
+	}{
+		{name: "no requirement", policy: &pb.MethodAuth{}, claims: &Claims{}, ok: true},
+		{name: "nil policy", policy: nil, claims: &Claims{}, ok: true},
+		{
+			name:   "one of the roles",
+			policy: &pb.MethodAuth{Roles: []string{"admin", "editor"}},
+			claims: &Claims{Roles: []string{"viewer", "editor"}},
+			ok:     true,
+		},
+		{
+			name:   "none of the roles",
+			policy: &pb.MethodAuth{Roles: []string{"admin", "editor"}},
+			claims: &Claims{Roles: []string{"viewer"}},
+		},
+		{
+			name:   "roles are case sensitive",
+			policy: &pb.MethodAuth{Roles: []string{"admin"}},
+			claims: &Claims{Roles: []string{"Admin"}},
+		},
+		{
+			name:   "permissions from the permissions claim",
+			policy: &pb.MethodAuth{Permissions: []string{"examples:read", "examples:write"}},
+			claims: &Claims{Permissions: []string{"examples:write", "examples:read"}},
+			ok:     true,
+		},
+		{
+			name:   "permissions from the scope",
+			policy: &pb.MethodAuth{Permissions: []string{"examples:read", "examples:write"}},
+			claims: &Claims{Scope: "openid examples:read  examples:write"},
+			ok:     true,
+		},
+		{
+			name:   "permissions from both claims",
+			policy: &pb.MethodAuth{Permissions: []string{"examples:read", "examples:write"}},
+			claims: &Claims{Scope: "examples:read", Permissions: []string{"examples:write"}},
+			ok:     true,
+		},
+		{
+			name:   "every permission is required",
+			policy: &pb.MethodAuth{Permissions: []string{"examples:read", "examples:write"}},
+			claims: &Claims{Scope: "examples:read"},
+		},
+		{
+			name:   "scope is matched by whole words",
+			policy: &pb.MethodAuth{Permissions: []string{"examples:read"}},
+			claims: &Claims{Scope: "examples:read:all"},
+		},
+		{
+			name:   "role and permissions",
+			policy: &pb.MethodAuth{Roles: []string{"admin"}, Permissions: []string{"keys:write"}},
+			claims: &Claims{Roles: []string{"admin"}, Permissions: []string{"keys:write"}},
+			ok:     true,
+		},
+		{
+			name:   "role without the permissions",
+			policy: &pb.MethodAuth{Roles: []string{"admin"}, Permissions: []string{"keys:write"}},
+			claims: &Claims{Roles: []string{"admin"}},
+		},
+		{
+			name:   "permissions without the role",
+			policy: &pb.MethodAuth{Roles: []string{"admin"}, Permissions: []string{"keys:write"}},
+			claims: &Claims{Permissions: []string{"keys:write"}},
+		},
+	}
+	for _, tt := range tests {
+		err := Authorize(tt.policy, tt.claims)
+		if tt.ok && err != nil {
+			t.Errorf("%s: Authorize = %v, want nil", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: Authorize succeeded, want an error", tt.name)
+		}
+	}
+}
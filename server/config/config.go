@@ -0,0 +1,324 @@
+// Package config defines the service configuration and loads it once at
+// startup.
+//
+// Every setting is declared as a tagged struct field:
+//
+//	env      environment variable (also read from the .env file)
+//	flag     command-line flag
+//	file     key in the optional YAML/JSON config file, nested by section
+//	default  value used when no source sets the field
+//	required the field must be set by one of the sources
+//	usage    help text shown for the flag
+//
+// Sources are applied in increasing order of precedence: defaults, config
+// file, .env file, environment variables, command-line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Config is the complete service configuration.
+type Config struct {
+	// File is the path of the optional YAML or JSON config file.
+	File string `env:"CONFIG_FILE" flag:"config" usage:"path to a YAML or JSON config file"`
+
+	Log  LogConfig  `file:"log"`
+	HTTP HTTPConfig `file:"http"`
+	GRPC GRPCConfig `file:"grpc"`
+	DB   DBConfig   `file:"db"`
+	TLS  TLSConfig  `file:"tls"`
+
+	Admin     AdminConfig     `file:"admin"`
+	Auth      AuthConfig      `file:"auth"`
+	Tracing   TracingConfig   `file:"tracing"`
+	RateLimit RateLimitConfig `file:"rate_limit"`
+	Health    HealthConfig    `file:"health"`
+	Shutdown  ShutdownConfig  `file:"shutdown"`
+}
+
+// LogConfig configures the structured logger.
+type LogConfig struct {
+	Format string `env:"LOG_FORMAT" flag:"log-format" file:"format" default:"json" usage:"log output format, json or text"`
+	Level  string `env:"LOG_LEVEL" flag:"log-level" file:"level" default:"info" usage:"minimum log level: debug, info, warn or error"`
+}
+
+// HTTPConfig configures the HTTP gateway listener.
+type HTTPConfig struct {
+	Addr string `env:"HTTP_ADDR" flag:"http-addr" file:"addr" default:":8080" usage:"HTTP gateway listen address"`
+	// SinglePort serves native gRPC and gRPC-Web next to the REST gateway
+	// on Addr, and the gateway calls the API in-process.
+	SinglePort     bool     `env:"HTTP_SINGLE_PORT" flag:"http-single-port" file:"single_port" default:"false" usage:"serve gRPC, gRPC-Web and REST all on HTTP_ADDR"`
+	GRPCWebOrigins []string `env:"HTTP_GRPC_WEB_ORIGINS" flag:"http-grpc-web-origins" file:"grpc_web_origins" usage:"origins allowed to make cross-origin gRPC-Web calls, * for any"`
+}
+
+// GRPCConfig configures the gRPC listener and how the gateway reaches it.
+type GRPCConfig struct {
+	Addr            string `env:"GRPC_ADDR" flag:"grpc-addr" file:"addr" default:":9090" usage:"gRPC listen address"`
+	GatewayEndpoint string `env:"GRPC_GATEWAY_ENDPOINT" flag:"grpc-gateway-endpoint" file:"gateway_endpoint" default:"localhost:9090" usage:"gRPC address the HTTP gateway dials"`
+}
+
+// TLSConfig configures TLS on the HTTP and gRPC listeners and on the
+// gateway's connection to the gRPC server. Files are reloaded when they
+// change on disk.
+type TLSConfig struct {
+	Enabled        bool          `env:"TLS_ENABLED" flag:"tls-enabled" file:"enabled" default:"false" usage:"serve HTTP and gRPC over TLS"`
+	CertFile       string        `env:"TLS_CERT_FILE" flag:"tls-cert-file" file:"cert_file" usage:"server certificate (PEM)"`
+	KeyFile        string        `env:"TLS_KEY_FILE" flag:"tls-key-file" file:"key_file" usage:"server private key (PEM)"`
+	ClientCAFile   string        `env:"TLS_CLIENT_CA_FILE" flag:"tls-client-ca-file" file:"client_ca_file" usage:"CA bundle gRPC client certificates must chain to, enables mTLS"`
+	HTTPClientAuth bool          `env:"TLS_HTTP_CLIENT_AUTH" flag:"tls-http-client-auth" file:"http_client_auth" default:"false" usage:"also require client certificates on the HTTP listener"`
+	CAFile         string        `env:"TLS_CA_FILE" flag:"tls-ca-file" file:"ca_file" usage:"CA bundle the gateway verifies the gRPC server with, system roots if empty"`
+	ClientCertFile string        `env:"TLS_CLIENT_CERT_FILE" flag:"tls-client-cert-file" file:"client_cert_file" usage:"certificate the gateway presents to the gRPC server, the server certificate if empty"`
+	ClientKeyFile  string        `env:"TLS_CLIENT_KEY_FILE" flag:"tls-client-key-file" file:"client_key_file" usage:"private key of TLS_CLIENT_CERT_FILE"`
+	ServerName     string        `env:"TLS_SERVER_NAME" flag:"tls-server-name" file:"server_name" usage:"name the gateway expects in the gRPC server certificate, the GRPC_GATEWAY_ENDPOINT host if empty"`
+	ReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" flag:"tls-reload-interval" file:"reload_interval" default:"30s" usage:"how often certificate files are checked for changes"`
+}
+
+// DBConfig configures the main database connection.
+type DBConfig struct {
+	Driver          string        `env:"DB_DRIVER" flag:"db-driver" file:"driver" default:"postgres" usage:"database driver: postgres, sqlite or mysql"`
+	DSN             string        `env:"DB_DSN" flag:"db-dsn" file:"dsn" required:"true" usage:"main database DSN, a file name or file::memory: for sqlite"`
+	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" file:"max_open_conns" default:"25" usage:"maximum open connections, 0 is unlimited"`
+	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" file:"max_idle_conns" default:"10" usage:"maximum idle connections kept for reuse"`
+	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" file:"conn_max_lifetime" default:"30m" usage:"maximum time a connection is reused, 0 is forever"`
+	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" flag:"db-conn-max-idle-time" file:"conn_max_idle_time" default:"5m" usage:"maximum time a connection stays idle, 0 is forever"`
+	ConnectTimeout  time.Duration `env:"DB_CONNECT_TIMEOUT" flag:"db-connect-timeout" file:"connect_timeout" default:"1m" usage:"how long to retry connecting at startup, with exponential backoff, before giving up"`
+	ReplicaDSNs     []string      `env:"DB_REPLICA_DSNS" flag:"db-replica-dsns" file:"replica_dsns" usage:"DSNs of read replicas of the same driver, read-only queries are spread over them"`
+}
+
+// AdminConfig configures the admin listener serving operational endpoints
+// such as /metrics, kept off the public HTTP port.
+type AdminConfig struct {
+	Addr string `env:"ADMIN_ADDR" flag:"admin-addr" file:"addr" default:":8081" usage:"admin listen address serving /metrics"`
+}
+
+// AuthConfig configures the JWT bearer authentication of RPCs.
+type AuthConfig struct {
+	Enabled     bool          `env:"AUTH_ENABLED" flag:"auth-enabled" file:"enabled" default:"false" usage:"require a valid JWT on every non-public RPC"`
+	HMACSecret  string        `env:"AUTH_JWT_SECRET" flag:"auth-jwt-secret" file:"jwt_secret" usage:"shared secret of HS256 tokens"`
+	JWKS        string        `env:"AUTH_JWKS" flag:"auth-jwks" file:"jwks" usage:"path or http(s) URL of the JWKS holding the RS256 public keys"`
+	JWKSRefresh time.Duration `env:"AUTH_JWKS_REFRESH" flag:"auth-jwks-refresh" file:"jwks_refresh" default:"15m" usage:"how often a JWKS URL is fetched again"`
+	Issuer      string        `env:"AUTH_JWT_ISSUER" flag:"auth-jwt-issuer" file:"issuer" usage:"required iss claim, empty accepts any"`
+	Audience    string        `env:"AUTH_JWT_AUDIENCE" flag:"auth-jwt-audience" file:"audience" usage:"required aud claim, empty accepts any"`
+	Leeway      time.Duration `env:"AUTH_JWT_LEEWAY" flag:"auth-jwt-leeway" file:"leeway" default:"30s" usage:"clock skew tolerated on exp, nbf and iat"`
+	APIKeys     bool          `env:"AUTH_API_KEYS" flag:"auth-api-keys" file:"api_keys" default:"true" usage:"also accept API keys sent in x-api-key"`
+}
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	Exporter    string  `env:"TRACING_EXPORTER" flag:"tracing-exporter" file:"exporter" default:"none" usage:"span exporter: none, stdout, file or a registered one"`
+	File        string  `env:"TRACING_FILE" flag:"tracing-file" file:"file" default:"traces.jsonl" usage:"file the file exporter appends spans to"`
+	ServiceName string  `env:"TRACING_SERVICE_NAME" flag:"tracing-service-name" file:"service_name" default:"micro-svc-template" usage:"service.name reported on spans"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" flag:"tracing-sample-ratio" file:"sample_ratio" default:"1" usage:"fraction of new traces sampled, from 0 to 1"`
+}
+
+// RateLimitConfig configures the token buckets limiting each caller, keyed
+// by API key, token subject or client IP.
+type RateLimitConfig struct {
+	Enabled bool          `env:"RATE_LIMIT_ENABLED" flag:"rate-limit-enabled" file:"enabled" default:"false" usage:"rate limit RPCs per caller and method"`
+	RPS     float64       `env:"RATE_LIMIT_RPS" flag:"rate-limit-rps" file:"rps" default:"10" usage:"default requests per second of a caller on a method"`
+	Burst   int           `env:"RATE_LIMIT_BURST" flag:"rate-limit-burst" file:"burst" default:"20" usage:"default requests a caller may make at once on a method"`
+	Methods []MethodLimit `env:"RATE_LIMIT_METHODS" flag:"rate-limit-methods" file:"methods" usage:"per method limits as Method=rps:burst, overriding the rate_limit option"`
+	// PreAuthRPS and PreAuthBurst limit every RPC of a client IP before
+	// authentication, so callers failing it are limited too.
+	PreAuthRPS   float64 `env:"RATE_LIMIT_PRE_AUTH_RPS" flag:"rate-limit-pre-auth-rps" file:"pre_auth_rps" default:"20" usage:"RPCs per second of a client IP before authentication, 0 disables"`
+	PreAuthBurst int     `env:"RATE_LIMIT_PRE_AUTH_BURST" flag:"rate-limit-pre-auth-burst" file:"pre_auth_burst" default:"40" usage:"RPCs a client IP may make at once before authentication"`
+	// HTTPRPS and HTTPBurst limit every HTTP request of a client IP, before
+	// the gateway, on top of the per method limits.
+	HTTPRPS   float64 `env:"RATE_LIMIT_HTTP_RPS" flag:"rate-limit-http-rps" file:"http_rps" default:"0" usage:"requests per second of a client IP on the HTTP port, 0 disables"`
+	HTTPBurst int     `env:"RATE_LIMIT_HTTP_BURST" flag:"rate-limit-http-burst" file:"http_burst" default:"50" usage:"requests a client IP may make at once on the HTTP port"`
+	// TrustedProxies are the proxies whose X-Forwarded-For entries are
+	// believed, besides loopback.
+	TrustedProxies []string `env:"RATE_LIMIT_TRUSTED_PROXIES" flag:"rate-limit-trusted-proxies" file:"trusted_proxies" usage:"IP addresses or CIDR ranges of the proxies in front of the service, whose X-Forwarded-For entries are trusted"`
+}
+
+// MethodLimit is the rate limit of a method, written Method=rps:burst.
+type MethodLimit struct {
+	// Method is the method name (CreateExample) or the full gRPC method
+	// (/package.Service/Method).
+	Method string
+	RPS    float64
+	Burst  int
+}
+
+// UnmarshalText parses a Method=rps:burst limit.
+func (m *MethodLimit) UnmarshalText(text []byte) error {
+	spec := string(text)
+	method, value, ok := strings.Cut(spec, "=")
+	rateStr, burstStr, ok2 := strings.Cut(value, ":")
+	if !ok || !ok2 || strings.TrimSpace(method) == "" {
+		return fmt.Errorf("invalid rate limit %q, want Method=rps:burst", spec)
+	}
+	rps, err := strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+	if err != nil || rps < 0 || math.IsInf(rps, 0) || math.IsNaN(rps) {
+		return fmt.Errorf("invalid rps in %q", spec)
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(burstStr))
+	if err != nil || burst < 0 {
+		return fmt.Errorf("invalid burst in %q", spec)
+	}
+	*m = MethodLimit{Method: strings.TrimSpace(method), RPS: rps, Burst: burst}
+
+	return nil
+}
+
+// HealthConfig configures the readiness checks.
+type HealthConfig struct {
+	Interval time.Duration `env:"HEALTH_CHECK_INTERVAL" flag:"health-check-interval" file:"interval" default:"5s" usage:"how often the gRPC health status is re-evaluated"`
+	Timeout  time.Duration `env:"HEALTH_CHECK_TIMEOUT" flag:"health-check-timeout" file:"timeout" default:"2s" usage:"time allowed for all readiness checks together"`
+}
+
+// ShutdownConfig configures the graceful shutdown on SIGTERM/SIGINT.
+type ShutdownConfig struct {
+	Timeout time.Duration `env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" file:"timeout" default:"30s" usage:"maximum time to drain in-flight requests and close resources on shutdown"`
+}
+
+// validate checks values that are well-formed but not acceptable. Problems
+// are reported through add with the name of the offending key.
+func (c *Config) validate(add func(key, problem string)) {
+	if f := strings.ToLower(c.Log.Format); f != "json" && f != "text" {
+		add("LOG_FORMAT", fmt.Sprintf("must be json or text, got %q", c.Log.Format))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		add("LOG_LEVEL", fmt.Sprintf("must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+
+	validateAddr("HTTP_ADDR", c.HTTP.Addr, add)
+	if !c.HTTP.SinglePort {
+		validateAddr("GRPC_ADDR", c.GRPC.Addr, add)
+		validateAddr("GRPC_GATEWAY_ENDPOINT", c.GRPC.GatewayEndpoint, add)
+	}
+	validateAddr("ADMIN_ADDR", c.Admin.Addr, add)
+
+	if c.TLS.Enabled {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			add("TLS_ENABLED", "requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		if c.TLS.HTTPClientAuth && c.TLS.ClientCAFile == "" {
+			add("TLS_HTTP_CLIENT_AUTH", "requires TLS_CLIENT_CA_FILE")
+		}
+		if (c.TLS.ClientCertFile == "") != (c.TLS.ClientKeyFile == "") {
+			add("TLS_CLIENT_CERT_FILE", "must be set together with TLS_CLIENT_KEY_FILE")
+		}
+	}
+	if c.TLS.ReloadInterval <= 0 {
+		add("TLS_RELOAD_INTERVAL", "must be positive")
+	}
+	switch c.DB.Driver {
+	case "postgres", "sqlite", "mysql":
+	default:
+		add("DB_DRIVER", fmt.Sprintf("must be postgres, sqlite or mysql, got %q", c.DB.Driver))
+	}
+	if c.DB.DSN != "" {
+		validateDSN("DB_DSN", c.DB.Driver, c.DB.DSN, add)
+	}
+	for _, dsn := range c.DB.ReplicaDSNs {
+		validateDSN("DB_REPLICA_DSNS", c.DB.Driver, dsn, add)
+	}
+	if c.DB.MaxOpenConns < 0 {
+		add("DB_MAX_OPEN_CONNS", "must not be negative")
+	}
+	if c.DB.MaxIdleConns < 0 {
+		add("DB_MAX_IDLE_CONNS", "must not be negative")
+	}
+	if c.DB.ConnMaxLifetime < 0 {
+		add("DB_CONN_MAX_LIFETIME", "must not be negative")
+	}
+	if c.DB.ConnMaxIdleTime < 0 {
+		add("DB_CONN_MAX_IDLE_TIME", "must not be negative")
+	}
+	if c.DB.ConnectTimeout < 0 {
+		add("DB_CONNECT_TIMEOUT", "must not be negative")
+	}
+	if c.Auth.Enabled && c.Auth.HMACSecret == "" && c.Auth.JWKS == "" && !c.Auth.APIKeys {
+		add("AUTH_ENABLED", "requires AUTH_JWT_SECRET, AUTH_JWKS or AUTH_API_KEYS")
+	}
+	if c.Auth.JWKSRefresh <= 0 {
+		add("AUTH_JWKS_REFRESH", "must be positive")
+	}
+	if c.Auth.Leeway < 0 {
+		add("AUTH_JWT_LEEWAY", "must not be negative")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("TRACING_SAMPLE_RATIO", "must be between 0 and 1")
+	}
+	if c.RateLimit.RPS < 0 {
+		add("RATE_LIMIT_RPS", "must not be negative")
+	}
+	if c.RateLimit.Burst < 0 {
+		add("RATE_LIMIT_BURST", "must not be negative")
+	}
+	if c.RateLimit.PreAuthRPS < 0 {
+		add("RATE_LIMIT_PRE_AUTH_RPS", "must not be negative")
+	}
+	if c.RateLimit.PreAuthBurst < 0 {
+		add("RATE_LIMIT_PRE_AUTH_BURST", "must not be negative")
+	}
+	if c.RateLimit.HTTPRPS < 0 {
+		add("RATE_LIMIT_HTTP_RPS", "must not be negative")
+	}
+	if c.RateLimit.HTTPBurst < 0 {
+		add("RATE_LIMIT_HTTP_BURST", "must not be negative")
+	}
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		if _, err := netip.ParseAddr(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			add("RATE_LIMIT_TRUSTED_PROXIES", fmt.Sprintf("invalid proxy %q, want an IP address or a CIDR range", proxy))
+		}
+	}
+	if c.Health.Interval <= 0 {
+		add("HEALTH_CHECK_INTERVAL", "must be positive")
+	}
+	if c.Health.Timeout <= 0 {
+		add("HEALTH_CHECK_TIMEOUT", "must be positive")
+	}
+	if c.Shutdown.Timeout <= 0 {
+		add("SHUTDOWN_TIMEOUT", "must be positive")
+	}
+}
+
+func validateAddr(key, addr string, add func(key, problem string)) {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		add(key, fmt.Sprintf("invalid address %q: %v", addr, err))
+		return
+	}
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		add(key, fmt.Sprintf("invalid port %q", port))
+	}
+}
+
+// validateDSN reports a DSN the driver cannot parse. SQLite takes any file
+// name.
+func validateDSN(key, driver, dsn string, add func(key, problem string)) {
+	var err error
+	switch driver {
+	case "postgres":
+		_, err = pgconn.ParseConfig(dsn)
+	case "mysql":
+		_, err = mysql.ParseDSN(dsn)
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// The URL error repeats the DSN, password included
+		err = urlErr.Err
+	}
+	if err != nil {
+		add(key, fmt.Sprintf("invalid %s DSN: %v", driver, err))
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMethodLimit(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    MethodLimit
+		wantErr bool
+	}{
+		{spec: "CreateExample=1:5", want: MethodLimit{Method: "CreateExample", RPS: 1, Burst: 5}},
+		{spec: " ListExamples = 0.5 : 10 ", want: MethodLimit{Method: "ListExamples", RPS: 0.5, Burst: 10}},
+		{spec: "/pkg.Service/Method=0:0", want: MethodLimit{Method: "/pkg.Service/Method"}},
+		{spec: "CreateExample", wantErr: true},
+		{spec: "CreateExample=1", wantErr: true},
+		{spec: "=1:5", wantErr: true},
+		{spec: "CreateExample=fast:5", wantErr: true},
+		{spec: "CreateExample=-1:5", wantErr: true},
+		{spec: "CreateExample=NaN:5", wantErr: true},
+		{spec: "CreateExample=1:1.5", wantErr: true},
+		{spec: "CreateExample=1:-5", wantErr: true},
+	}
+	for _, tt := range tests {
+		var got MethodLimit
+		err := got.UnmarshalText([]byte(tt.spec))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("UnmarshalText(%q) = %+v, want an error", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("UnmarshalText(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadRateLimit(t *testing.T) {
+	t.Setenv("DB_DSN", "postgres://localhost/test")
+	t.Setenv("RATE_LIMIT_METHODS", "CreateExample=1:5, ListExamples=50:100")
+	t.Setenv("RATE_LIMIT_TRUSTED_PROXIES", "10.0.0.0/8,192.0.2.1")
+	cfg, _, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := []MethodLimit{
+		{Method: "CreateExample", RPS: 1, Burst: 5},
+		{Method: "ListExamples", RPS: 50, Burst: 100},
+	}
+	if !reflect.DeepEqual(cfg.RateLimit.Methods, want) {
+		t.Errorf("Methods = %+v, want %+v", cfg.RateLimit.Methods, want)
+	}
+	if !reflect.DeepEqual(cfg.RateLimit.TrustedProxies, []string{"10.0.0.0/8", "192.0.2.1"}) {
+		t.Errorf("TrustedProxies = %v", cfg.RateLimit.TrustedProxies)
+	}
+
+	t.Setenv("RATE_LIMIT_METHODS", "CreateExample=1")
+	t.Setenv("RATE_LIMIT_TRUSTED_PROXIES", "proxy.internal")
+	_, _, err = Load(nil)
+	verr, ok := err.(*ValidationError)
+	if !ok || len(verr.Problems) != 2 {
+		t.Fatalf("Load = %v, want the two invalid settings", err)
+	}
+}
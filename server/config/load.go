@@ -0,0 +1,329 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError lists every missing or invalid configuration key.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// setting is a single tagged field of Config.
+type setting struct {
+	env      string
+	flag     string
+	file     string
+	def      string
+	required bool
+	usage    string
+	value    reflect.Value
+	set      bool
+}
+
+// key is the name the setting is reported under in validation errors.
+func (s *setting) key() string {
+	if s.env != "" {
+		return s.env
+	}
+	if s.flag != "" {
+		return "-" + s.flag
+	}
+
+	return s.file
+}
+
+// Load builds the configuration from defaults, the config file, the .env
+// file, environment variables and the command-line flags in args, in that
+// order of precedence. It returns the arguments left after the flags.
+func Load(args []string) (*Config, []string, error) {
+	cfg := &Config{}
+	settings := collect(reflect.ValueOf(cfg).Elem(), "")
+	problems := []string{}
+	add := func(key, problem string) {
+		problems = append(problems, key+": "+problem)
+	}
+
+	// Flags are parsed first because they may name the config file, but are
+	// applied last.
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	flagValues := map[*setting]string{}
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		s := s
+		flags.Var(&flagValue{setting: s, values: flagValues}, s.flag, s.usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	for _, s := range settings {
+		if s.def != "" {
+			if err := setValue(s.value, s.def); err != nil {
+				return nil, nil, fmt.Errorf("config: bad default for %s: %w", s.key(), err)
+			}
+		}
+	}
+
+	// godotenv does not override variables that are already set, which
+	// gives the real environment precedence over the .env file.
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		add(".env", err.Error())
+	}
+
+	file := os.Getenv("CONFIG_FILE")
+	for s, v := range flagValues {
+		if s.flag == "config" {
+			file = v
+		}
+	}
+	if file != "" {
+		cfg.File = file
+		if err := loadFile(file, settings, add); err != nil {
+			add(file, err.Error())
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok && s.env != "" {
+			apply(s, v, s.env, add)
+		}
+	}
+	for s, v := range flagValues {
+		apply(s, v, "-"+s.flag, add)
+	}
+
+	for _, s := range settings {
+		if s.required && !s.set {
+			add(s.key(), "required but not set")
+		}
+	}
+	cfg.validate(add)
+
+	if len(problems) > 0 {
+		return nil, nil, &ValidationError{Problems: problems}
+	}
+
+	return cfg, flags.Args(), nil
+}
+
+// collect returns the tagged settings of the struct v, recursing into
+// nested structs. prefix is the config file path of v.
+func collect(v reflect.Value, prefix string) []*setting {
+	var settings []*setting
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag := field.Tag
+		file := tag.Get("file")
+		if file != "" && prefix != "" {
+			file = prefix + "." + file
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			settings = append(settings, collect(v.Field(i), file)...)
+			continue
+		}
+
+		settings = append(settings, &setting{
+			env:      tag.Get("env"),
+			flag:     tag.Get("flag"),
+			file:     file,
+			def:      tag.Get("default"),
+			required: tag.Get("required") == "true",
+			usage:    tag.Get("usage"),
+			value:    v.Field(i),
+		})
+	}
+
+	return settings
+}
+
+func apply(s *setting, value, source string, add func(key, problem string)) {
+	if err := setValue(s.value, value); err != nil {
+		add(source, err.Error())
+		return
+	}
+	s.set = s.set || value != ""
+}
+
+// loadFile applies the settings found in a YAML or JSON file.
+func loadFile(path string, settings []*setting, add func(key, problem string)) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	raw := map[string]interface{}{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".json":
+		err = json.Unmarshal(content, &raw)
+	default:
+		return fmt.Errorf("unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{}
+	flatten("", raw, values)
+
+	byKey := map[string]*setting{}
+	for _, s := range settings {
+		if s.file != "" {
+			byKey[s.file] = s
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			add(path, fmt.Sprintf("unknown key %q", key))
+			continue
+		}
+		apply(s, values[key], path+": "+key, add)
+	}
+
+	return nil
+}
+
+// flatten turns nested maps into dotted keys with string values.
+func flatten(prefix string, in map[string]interface{}, out map[string]string) {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flatten(key, v, out)
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			out[key] = strings.Join(items, ",")
+		case nil:
+			out[key] = ""
+		case float64:
+			out[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// setValue parses s into v according to its type.
+func setValue(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, item); err != nil {
+				return err
+			}
+			items = reflect.Append(items, elem)
+		}
+		v.Set(items)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// flagValue records a command-line flag so it can be applied after the
+// other sources.
+type flagValue struct {
+	setting *setting
+	values  map[*setting]string
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.setting == nil {
+		return ""
+	}
+
+	return f.setting.def
+}
+
+func (f *flagValue) Set(s string) error {
+	if err := setValue(reflect.New(f.setting.value.Type()).Elem(), s); err != nil {
+		return err
+	}
+	f.values[f.setting] = s
+
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.setting.value.Kind() == reflect.Bool
+}
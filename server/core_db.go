@@ -1,38 +1,90 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand"
 	"os"
+	"regexp"
+	"sync/atomic"
+	"time"
 
-	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+	"github.com/sandisuryadi36/micro-svc-template/server/config"
+	"github.com/sandisuryadi36/micro-svc-template/server/db"
+	"github.com/sandisuryadi36/micro-svc-template/server/migration"
+	"github.com/sandisuryadi36/micro-svc-template/server/tracing"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// sqliteDriverName is the go-sqlite3 driver with foreign keys enforced, a
+// busy timeout and the REGEXP function used by the ~ filter operator.
+const sqliteDriverName = "sqlite3_service"
+
+func init() {
+	sql.Register(sqliteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if _, err := conn.Exec("PRAGMA foreign_keys = ON; PRAGMA busy_timeout = 5000", nil); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("regexp", regexp.MatchString, true)
+		},
+	})
+}
+
+const (
+	// dbConnectBackoff is the first delay between connection attempts at
+	// startup, doubled up to dbConnectMaxBackoff.
+	dbConnectBackoff    = 500 * time.Millisecond
+	dbConnectMaxBackoff = 15 * time.Second
+	// dbConnectPingTimeout bounds a single connection attempt.
+	dbConnectPingTimeout = 5 * time.Second
+)
+
 var (
-	dbMain    *gorm.DB
-	dbMainSQL *sql.DB
+	dbMain        *gorm.DB
+	dbMainSQL     *sql.DB
+	dbMainMonitor *db.Monitor
+
+	// dbReplicas is nil without DB_REPLICA_DSNS
+	dbReplicas    *db.Replicas
+	dbReplicasSQL []*sql.DB
 )
 
-func startDBConnection() {
+func startDBConnection(cfg config.DBConfig) {
 	log.Printf("Starting Db Connections...")
 
-	initDBMain()
-
+	initDBMain(cfg)
+	initDBReplicas(cfg)
 }
 
-func initDBMain() {
+func initDBMain(cfg config.DBConfig) {
 	log.Printf("Main Db - Connecting")
-	var err error
-	dbMain, err = gorm.Open(postgres.Open(GetEnv("DB_DSN", "")), &gorm.Config{})
+	dialector, err := openDialector(cfg.Driver, cfg.DSN)
+	if err != nil {
+		log.Fatalf("Invalid DB main config: %v", err)
+	}
+	// Connecting is retried below, the database may still be starting
+	dbMain, err = gorm.Open(dialector, &gorm.Config{DisableAutomaticPing: true})
 	if err != nil {
 		log.Fatalf("Failed connect to DB main: %v", err)
 		os.Exit(1)
 		return
 	}
 
+	// Trace every statement as a child span of the request
+	if err := dbMain.Use(&tracing.GormPlugin{DBName: "main"}); err != nil {
+		log.Fatalf("Failed to install tracing on DB main: %v", err)
+	}
+
 	dbMainSQL, err = dbMain.DB()
 	if err != nil {
 		log.Fatalf("Error cannot initiate connection to DB main: %v", err)
@@ -40,49 +92,165 @@ func initDBMain() {
 		return
 	}
 
-	dbMainSQL.SetMaxIdleConns(0)
-	dbMainSQL.SetMaxOpenConns(0)
+	setPool(dbMainSQL, cfg)
 
-	err = dbMainSQL.Ping()
+	err = waitForDB(context.Background(), "main", dbMainSQL, cfg.ConnectTimeout)
 	if err != nil {
 		log.Fatalf("Cannot ping DB main: %v", err)
 		os.Exit(1)
 		return
 	}
+	dbMainMonitor = db.NewMonitor("main", dbMainSQL.PingContext)
+
+	log.Printf("Main Db - Connected (%s)", cfg.Driver)
+}
 
-	log.Printf("Main Db - Connected")
+// waitForDB pings sqlDB until it answers, sleeping between attempts from
+// dbConnectBackoff doubling up to dbConnectMaxBackoff, and gives up with the
+// last error once timeout has passed. A zero timeout pings once.
+func waitForDB(ctx context.Context, name string, sqlDB *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	backoff := dbConnectBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, dbConnectPingTimeout)
+		err := sqlDB.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+		wait := min(backoff/2+time.Duration(rand.Int63n(int64(backoff))), remaining)
+		log.Printf("DB %s not reachable (attempt %d), retrying in %s: %v", name, attempt, wait.Round(time.Millisecond), err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, dbConnectMaxBackoff)
+	}
 }
 
-func closeDBMain() {
+// initDBReplicas connects the read replicas. They join the rotation once
+// their first health check passes, so a replica that is down at startup is
+// left out until it answers.
+func initDBReplicas(cfg config.DBConfig) {
+	if len(cfg.ReplicaDSNs) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(cfg.ReplicaDSNs))
+	dbs := make([]*gorm.DB, 0, len(cfg.ReplicaDSNs))
+	for i, dsn := range cfg.ReplicaDSNs {
+		name := fmt.Sprintf("replica_%d", i+1)
+		log.Printf("Replica Db %s - Connecting", name)
+		dialector, err := openDialector(cfg.Driver, dsn)
+		if err != nil {
+			log.Fatalf("Invalid DB %s config: %v", name, err)
+		}
+		replica, err := gorm.Open(dialector, &gorm.Config{DisableAutomaticPing: true})
+		if err != nil {
+			log.Fatalf("Failed connect to DB %s: %v", name, err)
+		}
+		if err := replica.Use(&tracing.GormPlugin{DBName: name}); err != nil {
+			log.Fatalf("Failed to install tracing on DB %s: %v", name, err)
+		}
+		replicaSQL, err := replica.DB()
+		if err != nil {
+			log.Fatalf("Error cannot initiate connection to DB %s: %v", name, err)
+		}
+		setPool(replicaSQL, cfg)
+
+		names = append(names, name)
+		dbs = append(dbs, replica)
+		dbReplicasSQL = append(dbReplicasSQL, replicaSQL)
+	}
+
+	dbReplicas = db.NewReplicas(names, dbs)
+	log.Printf("Replica Db - %d replicas", len(dbs))
+}
+
+func closeDBReplicas() error {
+	var errs []error
+	for _, replicaSQL := range dbReplicasSQL {
+		errs = append(errs, replicaSQL.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// setPool applies the connection pool settings of cfg to sqlDB.
+func setPool(sqlDB *sql.DB, cfg config.DBConfig) {
+	if cfg.Driver == "sqlite" {
+		// SQLite allows a single writer, more connections only trade
+		// waiting in the pool for "database is locked" errors, and an
+		// in-memory database lives only as long as its connection.
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		return
+	}
+
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// openDialector returns the GORM dialector of driver for dsn.
+func openDialector(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case "postgres":
+		return postgres.Open(dsn), nil
+	case "sqlite":
+		return &sqlite.Dialector{DriverName: sqliteDriverName, DSN: dsn}, nil
+	case "mysql":
+		// Timestamps are scanned into time.Time and migrations hold
+		// several statements, whatever the DSN says.
+		cfg, err := mysqldriver.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ParseTime = true
+		cfg.MultiStatements = true
+		return mysql.Open(cfg.FormatDSN()), nil
+	}
+
+	return nil, fmt.Errorf("unsupported driver %q", driver)
+}
+
+func closeDBMain() error {
 	log.Print("Closing DB Main Connection ... ")
 	if err := dbMainSQL.Close(); err != nil {
-		log.Fatalf("Error on disconnection with DB Main : %v", err)
+		log.Printf("Error on disconnection with DB Main : %v", err)
+		return err
 	}
 	log.Println("Closing DB Main Success")
+	return nil
 }
 
+// dbMigrated is set once migrateDB has applied the migrations, so the
+// readiness check does not read the schema on every probe.
+var dbMigrated atomic.Bool
+
+// migrateDB applies the pending migrations on the connection opened by
+// startDBConnection, which an in-memory SQLite database needs to keep its
+// schema.
 func migrateDB() error {
-	initDBMain()
-	defer closeDBMain()
-
-	migrator := dbMain.Migrator()
-	if migrator.HasTable(
-		// List table ORM from proto gorm
-		&pb.ExampleORM{},
-	) {
-		log.Println("Table already exists, no migration needed")
-		return nil
+	migrator, err := migration.New(dbMain)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
 	}
 
 	log.Println("Migration process begin...")
-	if err := dbMain.AutoMigrate(
-		// List table from proto gorm
-		&pb.ExampleORM{},
-	); err != nil {
+	if err := migrator.Up(context.Background()); err != nil {
 		log.Fatalf("Migration failed: %v", err)
 		os.Exit(1)
 	}
 
+	dbMigrated.Store(true)
 	log.Println("Migration process finished...")
 
 	return nil
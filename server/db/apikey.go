@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+
+	"gorm.io/gorm"
+)
+
+func (p *GormProvider) CreateAPIKey(ctx context.Context, data *pb.ApiKeyORM) (*pb.ApiKeyORM, error) {
+	now := time.Now()
+	data.CreatedAt = &now
+	if err := p.conn(ctx).Create(data).Error; err != nil {
+		return nil, apperr.FromDB(ctx, err)
+	}
+
+	return data, nil
+}
+
+func (p *GormProvider) ListAPIKeys(ctx context.Context) ([]*pb.ApiKeyORM, error) {
+	data := []*pb.ApiKeyORM{}
+	if err := p.reader(ctx).Order("id").Find(&data).Error; err != nil {
+		return nil, apperr.FromDB(ctx, err)
+	}
+
+	return data, nil
+}
+
+func (p *GormProvider) RevokeAPIKey(ctx context.Context, id uint64) error {
+	result := p.conn(ctx).Model(&pb.ApiKeyORM{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return apperr.FromDB(ctx, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apperr.NotFound("API key with id %d not found", id)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash implements auth.APIKeyStore. It reads the primary so that
+// a revoked key is refused at once, whatever the replication lag.
+func (p *GormProvider) GetAPIKeyByHash(ctx context.Context, hash string) (*pb.ApiKeyORM, error) {
+	data := &pb.ApiKeyORM{}
+	if err := p.conn(ctx).Where("key_hash = ?", hash).First(data).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, apperr.FromDB(ctx, err)
+	}
+
+	return data, nil
+}
+
+// TouchAPIKey implements auth.APIKeyStore.
+func (p *GormProvider) TouchAPIKey(ctx context.Context, id uint64, t time.Time) error {
+	err := p.conn(ctx).Model(&pb.ApiKeyORM{}).
+		Where("id = ?", id).
+		Update("last_used_at", t).Error
+	if err != nil {
+		return apperr.FromDB(ctx, err)
+	}
+
+	return nil
+}
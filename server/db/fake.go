@@ -0,0 +1,394 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+	"github.com/sandisuryadi36/micro-svc-template/server/ormutil"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+//go:generate go run ../../cmd/fakegen -interface Repository -fake Fake -store memory
+
+// memory is the in-memory Repository behind Fake. It follows the behaviour
+// of GormProvider: ids are assigned in sequence, deleted examples are hidden,
+// lists use the same ordering, filters and page tokens, and missing rows
+// fail with the same codes.NotFound errors.
+//
+// WithTx holds the lock of memory until fn returns, so transactions run one
+// at a time and other calls wait for them, and restores the data as it was
+// before fn when fn fails. Calls made with the context of fn from other
+// goroutines are not serialized with it. Filters compare Go values, so
+// string operators on timestamps are rejected instead of compared.
+type memory struct {
+	mu       sync.Mutex
+	examples map[uint64]*pb.ExampleORM
+	apiKeys  map[uint64]*pb.ApiKeyORM
+	// nextExampleID and nextAPIKeyID are the last ids assigned, one
+	// sequence per table like the database
+	nextExampleID uint64
+	nextAPIKeyID  uint64
+	schemas       sync.Map
+}
+
+// memoryTxKey is the context key of the memory running a transaction.
+type memoryTxKey struct{}
+
+// NewFake returns an empty Fake.
+func NewFake() *Fake {
+	return &Fake{
+		memory: &memory{
+			examples: map[uint64]*pb.ExampleORM{},
+			apiKeys:  map[uint64]*pb.ApiKeyORM{},
+		},
+	}
+}
+
+// lock locks m for a call and returns the unlock. Calls in a transaction
+// of m already hold the lock.
+func (m *memory) lock(ctx context.Context) func() {
+	if ctx.Value(memoryTxKey{}) == m {
+		return func() {}
+	}
+	m.mu.Lock()
+
+	return m.mu.Unlock
+}
+
+func (m *memory) WithTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return apperr.FromDB(ctx, err)
+	}
+	// Nested transactions only restore their own changes, like savepoints
+	if ctx.Value(memoryTxKey{}) != m {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		ctx = context.WithValue(ctx, memoryTxKey{}, m)
+	}
+
+	examples, apiKeys := cloneMap(m.examples), cloneMap(m.apiKeys)
+	nextExampleID, nextAPIKeyID := m.nextExampleID, m.nextAPIKeyID
+	restore := func() {
+		m.examples, m.apiKeys = examples, apiKeys
+		m.nextExampleID, m.nextAPIKeyID = nextExampleID, nextAPIKeyID
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			restore()
+			panic(r)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		restore()
+		return err
+	}
+
+	return nil
+}
+
+func (m *memory) CreateData(ctx context.Context, data *pb.ExampleORM) (*pb.ExampleORM, error) {
+	defer m.lock(ctx)()
+
+	now := time.Now()
+	data.CratedAt = &now
+	data.UpdatedAt = &now
+	if data.Id == 0 {
+		m.nextExampleID++
+		data.Id = m.nextExampleID
+	} else if _, ok := m.examples[data.Id]; ok {
+		return nil, apperr.Conflict("id already exists", apperr.Field("id", "already exists"))
+	}
+	m.examples[data.Id] = cloneRow(data)
+
+	return data, nil
+}
+
+func (m *memory) GetData(ctx context.Context, id uint64) (*pb.ExampleORM, error) {
+	defer m.lock(ctx)()
+
+	data, ok := m.examples[id]
+	if !ok || data.DeletedAt != nil {
+		return nil, apperr.NotFound("Data with id %d not found", id)
+	}
+
+	return cloneRow(data), nil
+}
+
+func (m *memory) GetDataForUpdate(ctx context.Context, id uint64) (*pb.ExampleORM, error) {
+	return m.GetData(ctx, id)
+}
+
+func (m *memory) ListData(ctx context.Context, opts ListOptions) ([]*pb.ExampleORM, PageInfo, error) {
+	var info PageInfo
+	sch, err := m.schema(&pb.ExampleORM{})
+	if err != nil {
+		return nil, info, apperr.Internal(ctx, err)
+	}
+	q, err := parseList(sch, opts, exampleColumns)
+	if err != nil {
+		return nil, info, err
+	}
+	// Filters are evaluated on the ORM struct, which atlas matches by Go
+	// field name
+	ormutil.RewriteFieldPaths(q.filtering, func(path []string) []string {
+		column, _ := ormutil.ColumnName(sch, path[0])
+		return []string{sch.LookUpField(column).Name}
+	})
+
+	unlock := m.lock(ctx)
+	var rows []*pb.ExampleORM
+	for _, data := range m.examples {
+		if data.DeletedAt != nil {
+			continue
+		}
+		if q.filtering.GetRoot() != nil {
+			ok, err := q.filtering.Filter(data)
+			if err != nil {
+				unlock()
+				return nil, info, apperr.InvalidArgument("Invalid filter", apperr.Field("filter", err.Error()))
+			}
+			if !ok {
+				continue
+			}
+		}
+		rows = append(rows, cloneRow(data))
+	}
+	unlock()
+
+	values := func(row *pb.ExampleORM) []interface{} {
+		v := make([]interface{}, len(q.keys))
+		for i, key := range q.keys {
+			v[i], _ = key.field.ValueOf(ctx, reflect.ValueOf(row))
+		}
+		return v
+	}
+	// compare keeps the first error, the sort only needs an order
+	var cmpErr error
+	compare := func(a, b []interface{}) int {
+		c, err := compareKeys(q.keys, a, b)
+		if err != nil && cmpErr == nil {
+			cmpErr = err
+		}
+		return c
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return compare(values(rows[i]), values(rows[j])) < 0
+	})
+	if opts.IncludeTotal {
+		info.TotalSize = int64(len(rows))
+	}
+	if q.after != nil {
+		start := sort.Search(len(rows), func(i int) bool {
+			return compare(values(rows[i]), q.after) > 0
+		})
+		rows = rows[start:]
+	}
+	if cmpErr != nil {
+		return nil, info, apperr.Internal(ctx, cmpErr)
+	}
+	if len(rows) > q.size {
+		rows = rows[:q.size]
+		info.NextPageToken, err = encodeCursor(ctx, q.keys, rows[q.size-1], q.id)
+		if err != nil {
+			return nil, info, apperr.Internal(ctx, err)
+		}
+	}
+	if rows == nil {
+		rows = []*pb.ExampleORM{}
+	}
+
+	return rows, info, nil
+}
+
+func (m *memory) UpdateData(ctx context.Context, data *pb.ExampleORM) (*pb.ExampleORM, error) {
+	defer m.lock(ctx)()
+
+	stored, ok := m.examples[data.Id]
+	if !ok || stored.DeletedAt != nil {
+		return nil, apperr.NotFound("Data with id %d not found", data.Id)
+	}
+	now := time.Now()
+	data.UpdatedAt = &now
+	stored.Name = data.Name
+	stored.Description = data.Description
+	stored.UpdatedAt = data.UpdatedAt
+
+	return data, nil
+}
+
+func (m *memory) DeleteData(ctx context.Context, id uint64) error {
+	defer m.lock(ctx)()
+
+	data, ok := m.examples[id]
+	if !ok || data.DeletedAt != nil {
+		return apperr.NotFound("Data with id %d not found", id)
+	}
+	now := time.Now()
+	data.DeletedAt = &now
+	data.UpdatedAt = &now
+
+	return nil
+}
+
+func (m *memory) CreateAPIKey(ctx context.Context, data *pb.ApiKeyORM) (*pb.ApiKeyORM, error) {
+	defer m.lock(ctx)()
+
+	for _, key := range m.apiKeys {
+		if key.KeyHash == data.KeyHash {
+			return nil, apperr.Conflict("key_hash already exists", apperr.Field("key_hash", "already exists"))
+		}
+	}
+	now := time.Now()
+	data.CreatedAt = &now
+	m.nextAPIKeyID++
+	data.Id = m.nextAPIKeyID
+	m.apiKeys[data.Id] = cloneRow(data)
+
+	return data, nil
+}
+
+func (m *memory) ListAPIKeys(ctx context.Context) ([]*pb.ApiKeyORM, error) {
+	defer m.lock(ctx)()
+
+	data := make([]*pb.ApiKeyORM, 0, len(m.apiKeys))
+	for _, key := range m.apiKeys {
+		data = append(data, cloneRow(key))
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].Id < data[j].Id })
+
+	return data, nil
+}
+
+func (m *memory) RevokeAPIKey(ctx context.Context, id uint64) error {
+	defer m.lock(ctx)()
+
+	key, ok := m.apiKeys[id]
+	if !ok || key.RevokedAt != nil {
+		return apperr.NotFound("API key with id %d not found", id)
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+
+	return nil
+}
+
+// GetAPIKeyByHash implements auth.APIKeyStore.
+func (m *memory) GetAPIKeyByHash(ctx context.Context, hash string) (*pb.ApiKeyORM, error) {
+	defer m.lock(ctx)()
+
+	for _, key := range m.apiKeys {
+		if key.KeyHash == hash {
+			return cloneRow(key), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// TouchAPIKey implements auth.APIKeyStore.
+func (m *memory) TouchAPIKey(ctx context.Context, id uint64, t time.Time) error {
+	defer m.lock(ctx)()
+
+	if key, ok := m.apiKeys[id]; ok {
+		key.LastUsedAt = &t
+	}
+
+	return nil
+}
+
+func (m *memory) schema(model interface{}) (*schema.Schema, error) {
+	return schema.Parse(model, &m.schemas, schema.NamingStrategy{})
+}
+
+// compareKeys compares two sort key values in the order of keys, NULLs
+// last like listPage.
+func compareKeys(keys []sortKey, a, b []interface{}) (int, error) {
+	for i, key := range keys {
+		if an, bn := isNull(a[i]), isNull(b[i]); an || bn {
+			if c := boolInt(an) - boolInt(bn); c != 0 {
+				return c, nil
+			}
+			continue
+		}
+		c, err := compareValues(a[i], b[i])
+		if err != nil {
+			return 0, err
+		}
+		if key.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func compareValues(a, b interface{}) (int, error) {
+	av, bv := reflect.Indirect(reflect.ValueOf(a)), reflect.Indirect(reflect.ValueOf(b))
+	if av.Type() != bv.Type() {
+		return 0, fmt.Errorf("db: cannot compare %s with %s", av.Type(), bv.Type())
+	}
+	if at, ok := av.Interface().(time.Time); ok {
+		return at.Compare(bv.Interface().(time.Time)), nil
+	}
+
+	switch av.Kind() {
+	case reflect.String:
+		return strings.Compare(av.String(), bv.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(av.Int(), bv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(av.Uint(), bv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(av.Float(), bv.Float()), nil
+	case reflect.Bool:
+		return boolInt(av.Bool()) - boolInt(bv.Bool()), nil
+	}
+
+	return 0, fmt.Errorf("db: cannot sort by %s", av.Type())
+}
+
+func compareOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// cloneRow copies an ORM row so callers cannot change the stored one.
+func cloneRow[T any](row *T) *T {
+	c := *row
+	return &c
+}
+
+func cloneMap[T any](m map[uint64]*T) map[uint64]*T {
+	c := make(map[uint64]*T, len(m))
+	for id, row := range m {
+		c[id] = cloneRow(row)
+	}
+
+	return c
+}
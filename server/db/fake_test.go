@@ -0,0 +1,89 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/db"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+func fakeNames(t *testing.T, f *db.Fake) []string {
+	t.Helper()
+	rows, _, err := f.ListData(context.Background(), db.ListOptions{OrderBy: "id"})
+	if err != nil {
+		t.Fatalf("ListData: %v", err)
+	}
+	var names []string
+	for _, row := range rows {
+		names = append(names, row.Name)
+	}
+
+	return names
+}
+
+func TestFakeWithTx(t *testing.T) {
+	ctx := context.Background()
+	f := db.NewFake()
+	errFail := errors.New("fail")
+
+	// A call made during a transaction waits for it, the rollback keeps
+	// its write
+	done := make(chan error)
+	err := f.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := f.CreateData(ctx, &pb.ExampleORM{Name: "rolled back"}); err != nil {
+			return err
+		}
+		go func() {
+			_, err := f.CreateData(context.Background(), &pb.ExampleORM{Name: "concurrent"})
+			done <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Errorf("WithTx = %v, want %v", err, errFail)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("concurrent CreateData: %v", err)
+	}
+
+	// A nested transaction only undoes its own changes
+	err = f.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := f.CreateData(ctx, &pb.ExampleORM{Name: "outer"}); err != nil {
+			return err
+		}
+		if err := f.WithTx(ctx, func(ctx context.Context) error {
+			f.CreateData(ctx, &pb.ExampleORM{Name: "nested"})
+			return errFail
+		}); !errors.Is(err, errFail) {
+			t.Errorf("nested WithTx = %v, want %v", err, errFail)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	if got, want := fakeNames(t, f), []string{"concurrent", "outer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("examples = %v, want %v", got, want)
+	}
+}
+
+func TestFakeFunc(t *testing.T) {
+	f := db.NewFake()
+	errDown := errors.New("database down")
+	f.GetDataFunc = func(ctx context.Context, id uint64) (*pb.ExampleORM, error) {
+		return nil, errDown
+	}
+
+	if _, err := f.GetData(context.Background(), 1); !errors.Is(err, errDown) {
+		t.Errorf("GetData = %v, want the error of GetDataFunc", err)
+	}
+	if _, err := f.CreateData(context.Background(), &pb.ExampleORM{Name: "a"}); err != nil {
+		t.Errorf("CreateData without CreateDataFunc: %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/infobloxopen/atlas-app-toolkit/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+	"github.com/sandisuryadi36/micro-svc-template/server/ormutil"
+)
+
+const (
+	// DefaultPageSize is the page size of a list without one.
+	DefaultPageSize = 20
+	// MaxPageSize caps the page size of a list.
+	MaxPageSize = 100
+)
+
+// ListOptions are the paging, filtering and sorting parameters of a list
+// query, as received from a list RPC.
+type ListOptions struct {
+	PageSize     int32
+	PageToken    string
+	Filter       string
+	OrderBy      string
+	IncludeTotal bool
+}
+
+// PageInfo describes the page returned by a list query.
+type PageInfo struct {
+	// NextPageToken fetches the following page, empty on the last one.
+	NextPageToken string
+	// TotalSize counts the rows matching the filter, with IncludeTotal.
+	TotalSize int64
+}
+
+// cursor is the content of a page token: the sort key of the last row of
+// the page, and a hash of the query it belongs to so a token is not reused
+// with another filter or order.
+type cursor struct {
+	Query  string            `json:"q"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// sortKey is a column of the order of a list. NULLs of a nullable column
+// come after every value, whatever the direction, so that all dialects
+// return the same order.
+type sortKey struct {
+	field    *schema.Field
+	desc     bool
+	nullable bool
+}
+
+// listQuery is a validated ListOptions.
+type listQuery struct {
+	filtering *query.Filtering
+	keys      []sortKey
+	// after holds the sort key of the last row of the previous page, nil
+	// on the first page.
+	after []interface{}
+	size  int
+	id    string
+}
+
+// parseList validates opts against the columns allowed for the rows of
+// sch. The primary key is always the last sort key so that rows are in a
+// total order.
+func parseList(sch *schema.Schema, opts ListOptions, allowed map[string]bool) (*listQuery, error) {
+	q := &listQuery{filtering: &query.Filtering{}, size: int(opts.PageSize)}
+	if q.size <= 0 {
+		q.size = DefaultPageSize
+	}
+	if q.size > MaxPageSize {
+		q.size = MaxPageSize
+	}
+
+	if strings.TrimSpace(opts.Filter) != "" {
+		var err error
+		q.filtering, err = query.ParseFiltering(opts.Filter)
+		if err != nil {
+			return nil, apperr.InvalidArgument("Invalid filter", apperr.Field("filter", err.Error()))
+		}
+	}
+	for _, path := range ormutil.FieldPaths(q.filtering) {
+		column, err := ormutil.ColumnName(sch, path)
+		if err != nil || !allowed[column] {
+			return nil, apperr.InvalidArgument("Invalid filter", apperr.Field("filter", fmt.Sprintf("cannot filter by %q", path)))
+		}
+	}
+
+	var err error
+	q.keys, err = sortKeys(sch, opts.OrderBy, allowed)
+	if err != nil {
+		return nil, apperr.InvalidArgument("Invalid order_by", apperr.Field("order_by", err.Error()))
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(opts.Filter + "\x00" + opts.OrderBy))
+	q.id = strconv.FormatUint(hash.Sum64(), 36)
+	if opts.PageToken != "" {
+		q.after, err = decodeCursor(q.keys, opts.PageToken, q.id)
+		if err != nil {
+			return nil, apperr.InvalidArgument("Invalid page_token", apperr.Field("page_token", err.Error()))
+		}
+	}
+
+	return q, nil
+}
+
+// listPage runs db, already restricted to the visible rows of T, as a
+// keyset paginated query. Filters and sorts may only use the columns
+// allowed.
+func listPage[T any](ctx context.Context, db *gorm.DB, opts ListOptions, allowed map[string]bool) ([]*T, PageInfo, error) {
+	var info PageInfo
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, info, apperr.Internal(ctx, err)
+	}
+	q, err := parseList(stmt.Schema, opts, allowed)
+	if err != nil {
+		return nil, info, err
+	}
+
+	db = db.Model(new(T))
+	db, err = ormutil.ApplyFiltering(ctx, db, q.filtering, new(T))
+	if err != nil {
+		return nil, info, apperr.InvalidArgument("Invalid filter", apperr.Field("filter", err.Error()))
+	}
+	if opts.IncludeTotal {
+		if err := db.Session(&gorm.Session{}).Count(&info.TotalSize).Error; err != nil {
+			return nil, info, apperr.FromDB(ctx, err)
+		}
+	}
+
+	if q.after != nil {
+		where, args := afterCondition(db, q.keys, q.after)
+		db = db.Where(where, args...)
+	}
+	for _, key := range q.keys {
+		order := db.Statement.Quote(key.field.DBName)
+		if key.nullable {
+			db = db.Order(order + " IS NULL")
+		}
+		if key.desc {
+			order += " DESC"
+		}
+		db = db.Order(order)
+	}
+
+	rows := []*T{}
+	if err := db.Limit(q.size + 1).Find(&rows).Error; err != nil {
+		return nil, info, apperr.FromDB(ctx, err)
+	}
+	if len(rows) > q.size {
+		rows = rows[:q.size]
+		info.NextPageToken, err = encodeCursor(ctx, q.keys, rows[q.size-1], q.id)
+		if err != nil {
+			return nil, info, apperr.Internal(ctx, err)
+		}
+	}
+
+	return rows, info, nil
+}
+
+// sortKeys parses orderBy, adding the primary key as the last key.
+func sortKeys(sch *schema.Schema, orderBy string, allowed map[string]bool) ([]sortKey, error) {
+	sorting := &query.Sorting{}
+	if strings.TrimSpace(orderBy) != "" {
+		var err error
+		sorting, err = query.ParseSorting(orderBy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var keys []sortKey
+	seen := map[string]bool{}
+	for _, criteria := range sorting.GetCriterias() {
+		column, err := ormutil.ColumnName(sch, criteria.GetTag())
+		if err != nil || !allowed[column] {
+			return nil, fmt.Errorf("cannot order by %q", criteria.GetTag())
+		}
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+		field := sch.LookUpField(column)
+		keys = append(keys, sortKey{
+			field:    field,
+			desc:     criteria.IsDesc(),
+			nullable: field.FieldType.Kind() == reflect.Ptr,
+		})
+	}
+	if pk := sch.PrioritizedPrimaryField; pk != nil && !seen[pk.DBName] {
+		keys = append(keys, sortKey{field: pk})
+	}
+
+	return keys, nil
+}
+
+// afterCondition returns the condition selecting the rows after the sort
+// key values in the order of keys: (k1 > v1) OR (k1 = v1 AND k2 > v2) OR
+// ..., with < for descending keys. As NULLs come last, the rows after a
+// value of a nullable key include the NULLs, and no row is after a NULL.
+func afterCondition(db *gorm.DB, keys []sortKey, values []interface{}) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	var equal []string
+	var equalArgs []interface{}
+	for i, key := range keys {
+		column := db.Statement.Quote(key.field.DBName)
+		if isNull(values[i]) {
+			equal = append(equal, column+" IS NULL")
+			continue
+		}
+
+		op := " > ?"
+		if key.desc {
+			op = " < ?"
+		}
+		after := column + op
+		if key.nullable {
+			after = "(" + after + " OR " + column + " IS NULL)"
+		}
+		parts := append(append([]string{}, equal...), after)
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+		args = append(append(args, equalArgs...), values[i])
+
+		equal = append(equal, column+" = ?")
+		equalArgs = append(equalArgs, values[i])
+	}
+
+	return strings.Join(terms, " OR "), args
+}
+
+// isNull reports whether a sort key value decoded from a cursor is NULL.
+func isNull(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return !rv.IsValid() || rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+func decodeCursor(keys []sortKey, token, queryID string) ([]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token")
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil || len(c.Values) != len(keys) {
+		return nil, fmt.Errorf("malformed token")
+	}
+	if c.Query != queryID {
+		return nil, fmt.Errorf("token belongs to another filter or order_by")
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		v := reflect.New(key.field.FieldType)
+		if err := json.Unmarshal(c.Values[i], v.Interface()); err != nil {
+			return nil, fmt.Errorf("malformed token")
+		}
+		values[i] = v.Elem().Interface()
+	}
+
+	return values, nil
+}
+
+func encodeCursor(ctx context.Context, keys []sortKey, row interface{}, queryID string) (string, error) {
+	c := cursor{Query: queryID}
+	rv := reflect.ValueOf(row)
+	for _, key := range keys {
+		value, _ := key.field.ValueOf(ctx, rv)
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		c.Values = append(c.Values, raw)
+	}
+
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
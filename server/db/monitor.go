@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Monitor pings a database in the background, logs when the connection is
+// lost and when it is back, and reports the outcome to readiness. The pool
+// reconnects by itself, the pings only make it try while the service is idle.
+type Monitor struct {
+	name string
+	ping func(ctx context.Context) error
+
+	mu       sync.RWMutex
+	err      error
+	since    time.Time
+	failures int
+}
+
+// NewMonitor returns a monitor of the database named name reached by ping,
+// such as sql.DB.PingContext. The database is considered up until a ping
+// fails.
+func NewMonitor(name string, ping func(ctx context.Context) error) *Monitor {
+	return &Monitor{name: name, ping: ping, since: time.Now()}
+}
+
+// Err returns nil when the last ping succeeded, else why the database is
+// down and for how long. It has the signature of a health.Check.
+func (m *Monitor) Err(context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("down since %s, %d failed pings: %w", m.since.Format(time.RFC3339), m.failures, m.err)
+}
+
+// Healthy reports whether the last ping succeeded.
+func (m *Monitor) Healthy() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.err == nil
+}
+
+// Check pings the database once, giving it at most timeout.
+func (m *Monitor) Check(ctx context.Context, timeout time.Duration) {
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := m.ping(pingCtx)
+	if err != nil && ctx.Err() != nil {
+		// Stopped watching, not a failure of the database
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case err != nil && m.err == nil:
+		slog.Warn("DB connection lost", slog.String("db", m.name), slog.String("error", err.Error()))
+		m.since = time.Now()
+		m.failures = 1
+	case err != nil:
+		m.failures++
+	case m.err != nil:
+		slog.Info("DB connection restored", slog.String("db", m.name),
+			slog.Duration("down", time.Since(m.since)),
+			slog.Int("failed_pings", m.failures),
+		)
+		m.since = time.Now()
+		m.failures = 0
+	}
+	m.err = err
+}
+
+// Watch pings the database every interval until ctx is done.
+func (m *Monitor) Watch(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m.Check(ctx, timeout)
+	}
+}
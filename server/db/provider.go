@@ -3,9 +3,12 @@ package db
 import "gorm.io/gorm"
 
 type GormProvider struct {
-	db_main *gorm.DB
+	db_main  *gorm.DB
+	replicas *Replicas
 }
 
-func NewProvider(db *gorm.DB) *GormProvider {
-	return &GormProvider{db_main: db}
-}
\ No newline at end of file
+// NewProvider returns a provider on the primary db. Read-only methods go to
+// replicas when it is not nil, see reader.
+func NewProvider(db *gorm.DB, replicas *Replicas) *GormProvider {
+	return &GormProvider{db_main: db, replicas: replicas}
+}
@@ -0,0 +1,90 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/db"
+	"github.com/sandisuryadi36/micro-svc-template/server/migration"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+// TestProvider runs the provider methods on the schema of the migrations.
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	gdb := openSQLite(t, nil)
+	m, err := migration.New(gdb)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	p := db.NewProvider(gdb, nil)
+
+	var created *pb.ExampleORM
+	err = p.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = p.CreateData(ctx, &pb.ExampleORM{Name: "a", Description: "first"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("CreateData: %v", err)
+	}
+	if _, err := p.CreateData(ctx, &pb.ExampleORM{Name: "b"}); err != nil {
+		t.Fatalf("CreateData: %v", err)
+	}
+
+	err = p.WithTx(ctx, func(ctx context.Context) error {
+		data, err := p.GetDataForUpdate(ctx, created.Id)
+		if err != nil {
+			return err
+		}
+		data.Description = ""
+		_, err = p.UpdateData(ctx, data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("UpdateData: %v", err)
+	}
+	got, err := p.GetData(ctx, created.Id)
+	if err != nil {
+		t.Fatalf("GetData: %v", err)
+	}
+	if got.Name != "a" || got.Description != "" || got.CratedAt == nil {
+		t.Errorf("GetData = %+v, want name a, no description and a creation time", got)
+	}
+
+	list, _, err := p.ListData(ctx, db.ListOptions{Filter: "name == 'b'", OrderBy: "name desc"})
+	if err != nil {
+		t.Fatalf("ListData: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "b" {
+		t.Errorf("ListData = %+v, want only b", list)
+	}
+
+	if err := p.DeleteData(ctx, created.Id); err != nil {
+		t.Fatalf("DeleteData: %v", err)
+	}
+	if _, err := p.GetData(ctx, created.Id); status.Code(err) != codes.NotFound {
+		t.Errorf("GetData after DeleteData = %v, want NotFound", err)
+	}
+	if _, err := p.UpdateData(ctx, created); status.Code(err) != codes.NotFound {
+		t.Errorf("UpdateData after DeleteData = %v, want NotFound", err)
+	}
+
+	key, err := p.CreateAPIKey(ctx, &pb.ApiKeyORM{Name: "key", KeyHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if _, err := p.CreateAPIKey(ctx, &pb.ApiKeyORM{Name: "copy", KeyHash: "hash"}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("CreateAPIKey with the same hash = %v, want AlreadyExists", err)
+	}
+	found, err := p.GetAPIKeyByHash(ctx, "hash")
+	if err != nil || found == nil || found.Id != key.Id {
+		t.Errorf("GetAPIKeyByHash = %+v, %v, want key %d", found, err, key.Id)
+	}
+}
@@ -2,31 +2,98 @@ package db
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
 	"github.com/sandisuryadi36/micro-svc-template/server/pb"
 
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func (p *GormProvider) BeginTx(ctx context.Context) *gorm.DB {
-	return p.db_main.Begin()
+func (p *GormProvider) CreateData(ctx context.Context, data *pb.ExampleORM) (*pb.ExampleORM, error) {
+	now := time.Now()
+	data.CratedAt = &now
+	data.UpdatedAt = &now
+	if err := p.conn(ctx).Create(data).Error; err != nil {
+		return nil, apperr.FromDB(ctx, err)
+	}
+
+	return data, nil
+}
+
+func (p *GormProvider) GetData(ctx context.Context, id uint64) (*pb.ExampleORM, error) {
+	data := &pb.ExampleORM{}
+	query := p.reader(ctx)
+	if err := query.Where("id = ? AND deleted_at IS NULL", id).First(data).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.NotFound("Data with id %d not found", id)
+		}
+		return nil, apperr.FromDB(ctx, err)
+	}
+
+	return data, nil
+}
+
+// GetDataForUpdate is GetData on the primary, locking the row until the end
+// of the WithTx transaction ctx carries so that concurrent updates wait for
+// each other. SQLite has no row locks and locks the whole database on the
+// first write instead.
+func (p *GormProvider) GetDataForUpdate(ctx context.Context, id uint64) (*pb.ExampleORM, error) {
+	data := &pb.ExampleORM{}
+	query := p.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"})
+	if err := query.Where("id = ? AND deleted_at IS NULL", id).First(data).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.NotFound("Data with id %d not found", id)
+		}
+		return nil, apperr.FromDB(ctx, err)
+	}
+
+	return data, nil
 }
 
-func (p *GormProvider) CreateData(ctx context.Context, tx *gorm.DB, data *pb.ExampleORM) (*pb.ExampleORM, error) {
-	if err := tx.Create(&data).Error; err != nil {
-				return nil, status.Errorf(codes.Internal, "Internal Error: %v", err)
-			}
-		
-			return data, nil
+// exampleColumns are the columns examples can be filtered and sorted by.
+var exampleColumns = map[string]bool{
+	"id":          true,
+	"name":        true,
+	"description": true,
+	"crated_at":   true,
+	"updated_at":  true,
 }
 
-func (p *GormProvider) ListAllData(ctx context.Context) ([]*pb.ExampleORM, error) {
-	data := []*pb.ExampleORM{}
-	query := p.db_main
-	if err := query.Order("id").Find(&data).Error; err != nil {
-		return nil, status.Errorf(codes.Internal, "Internal Error: %v", err)
+func (p *GormProvider) ListData(ctx context.Context, opts ListOptions) ([]*pb.ExampleORM, PageInfo, error) {
+	query := p.reader(ctx).Where("deleted_at IS NULL")
+	return listPage[pb.ExampleORM](ctx, query, opts, exampleColumns)
+}
+
+func (p *GormProvider) UpdateData(ctx context.Context, data *pb.ExampleORM) (*pb.ExampleORM, error) {
+	now := time.Now()
+	data.UpdatedAt = &now
+	result := p.conn(ctx).Model(&pb.ExampleORM{}).
+		Where("id = ? AND deleted_at IS NULL", data.Id).
+		Select("name", "description", "updated_at").
+		Updates(data)
+	if result.Error != nil {
+		return nil, apperr.FromDB(ctx, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperr.NotFound("Data with id %d not found", data.Id)
 	}
 
 	return data, nil
 }
+
+func (p *GormProvider) DeleteData(ctx context.Context, id uint64) error {
+	result := p.conn(ctx).Model(&pb.ExampleORM{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Update("deleted_at", time.Now())
+	if result.Error != nil {
+		return apperr.FromDB(ctx, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apperr.NotFound("Data with id %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Replicas spreads reads round-robin over read replicas, skipping the ones
+// that failed their last health check.
+type Replicas struct {
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	db      *gorm.DB
+	monitor *Monitor
+	// checked is set once a health check has completed, a replica joins
+	// the rotation only after its first one passes.
+	checked atomic.Bool
+}
+
+// NewReplicas returns the replicas dbs, keyed by name in logs. They are out
+// of rotation until Check or Watch has found them healthy.
+func NewReplicas(names []string, dbs []*gorm.DB) *Replicas {
+	r := &Replicas{}
+	for i, db := range dbs {
+		rep := &replica{db: db}
+		rep.monitor = NewMonitor(names[i], rep.ping)
+		r.replicas = append(r.replicas, rep)
+	}
+
+	return r
+}
+
+// pick returns the next healthy replica, or nil when there is none.
+func (r *Replicas) pick() *gorm.DB {
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		rep := r.replicas[(start+i)%n]
+		if rep.healthy() {
+			return rep.db
+		}
+	}
+
+	return nil
+}
+
+// Healthy returns how many replicas passed their last health check.
+func (r *Replicas) Healthy() int {
+	healthy := 0
+	for _, rep := range r.replicas {
+		if rep.healthy() {
+			healthy++
+		}
+	}
+
+	return healthy
+}
+
+// Check pings every replica, giving each at most timeout, and takes the ones
+// that fail out of rotation until they answer again.
+func (r *Replicas) Check(ctx context.Context, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, rep := range r.replicas {
+		wg.Add(1)
+		go func(rep *replica) {
+			defer wg.Done()
+			rep.monitor.Check(ctx, timeout)
+			if ctx.Err() == nil {
+				rep.checked.Store(true)
+			}
+		}(rep)
+	}
+	wg.Wait()
+}
+
+// Watch checks the replicas every interval until ctx is done.
+func (r *Replicas) Watch(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.Check(ctx, timeout)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rep *replica) healthy() bool {
+	return rep.checked.Load() && rep.monitor.Healthy()
+}
+
+func (rep *replica) ping(ctx context.Context) error {
+	sqlDB, err := rep.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+// primaryKey is the context key of WithPrimary.
+type primaryKey struct{}
+
+// WithPrimary returns a context whose reads go to the primary, for a caller
+// that must see its own writes despite the replication lag.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// reader returns the connection of a read-only query bound to ctx: the
+// transaction of ctx if any, the primary when ctx asks for it or no replica
+// is healthy, and otherwise the next replica.
+func (p *GormProvider) reader(ctx context.Context) *gorm.DB {
+	if _, ok := ctx.Value(txKey{}).(*txState); ok || p.replicas == nil {
+		return p.conn(ctx)
+	}
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return p.conn(ctx)
+	}
+	if db := p.replicas.pick(); db != nil {
+		return db.WithContext(ctx)
+	}
+
+	return p.conn(ctx)
+}
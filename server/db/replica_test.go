@@ -0,0 +1,101 @@
+package db_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/db"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+func TestReplicas(t *testing.T) {
+	ctx := context.Background()
+	// Every database holds a single example named after it, telling which
+	// one served a read
+	open := func(name string) *gorm.DB {
+		gdb := openExamples(t, nil)
+		if err := gdb.Create(&pb.ExampleORM{Name: name}).Error; err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		return gdb
+	}
+	primary := open("primary")
+	replica1, replica2 := open("replica_1"), open("replica_2")
+	replicas := db.NewReplicas([]string{"replica_1", "replica_2"}, []*gorm.DB{replica1, replica2})
+	p := db.NewProvider(primary, replicas)
+
+	reads := func(ctx context.Context) map[string]int {
+		t.Helper()
+		served := map[string]int{}
+		for i := 0; i < 4; i++ {
+			data, err := p.GetData(ctx, 1)
+			if err != nil {
+				t.Fatalf("GetData: %v", err)
+			}
+			served[data.Name]++
+		}
+		return served
+	}
+	assertReads := func(step string, got map[string]int, want map[string]int) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: reads served by %v, want %v", step, got, want)
+		}
+	}
+
+	assertReads("before the first check", reads(ctx), map[string]int{"primary": 4})
+	if n := replicas.Healthy(); n != 0 {
+		t.Errorf("Healthy before the first check = %d, want 0", n)
+	}
+
+	replicas.Check(ctx, time.Second)
+	if n := replicas.Healthy(); n != 2 {
+		t.Errorf("Healthy = %d, want 2", n)
+	}
+	assertReads("round robin", reads(ctx), map[string]int{"replica_1": 2, "replica_2": 2})
+	assertReads("primary asked for", reads(db.WithPrimary(ctx)), map[string]int{"primary": 4})
+	err := p.WithTx(ctx, func(ctx context.Context) error {
+		assertReads("in a transaction", reads(ctx), map[string]int{"primary": 4})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	// A replica failing its check is left out of rotation
+	closeDB(t, replica2)
+	replicas.Check(ctx, time.Second)
+	if n := replicas.Healthy(); n != 1 {
+		t.Errorf("Healthy with replica_2 down = %d, want 1", n)
+	}
+	assertReads("replica_2 down", reads(ctx), map[string]int{"replica_1": 4})
+
+	// Reads fall back to the primary when no replica is healthy
+	closeDB(t, replica1)
+	replicas.Check(ctx, time.Second)
+	assertReads("every replica down", reads(ctx), map[string]int{"primary": 4})
+}
+
+func TestReplicasCanceledCheck(t *testing.T) {
+	replicas := db.NewReplicas([]string{"replica_1"}, []*gorm.DB{openExamples(t, nil)})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	replicas.Check(ctx, time.Second)
+	if n := replicas.Healthy(); n != 0 {
+		t.Errorf("Healthy after a canceled check = %d, want 0", n)
+	}
+}
+
+// closeDB closes the connections of gdb so its pings fail.
+func closeDB(t *testing.T, gdb *gorm.DB) {
+	t.Helper()
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		t.Fatalf("DB: %v", err)
+	}
+	sqlDB.Close()
+}
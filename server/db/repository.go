@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/auth"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+// Repository is the data access of the API. GormProvider implements it on
+// the database and Fake in memory, for tests of the handlers.
+type Repository interface {
+	auth.APIKeyStore
+
+	// WithTx runs fn as a unit of work, see GormProvider.WithTx.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+
+	CreateData(ctx context.Context, data *pb.ExampleORM) (*pb.ExampleORM, error)
+	// GetData returns codes.NotFound for a missing or deleted example.
+	GetData(ctx context.Context, id uint64) (*pb.ExampleORM, error)
+	// GetDataForUpdate is GetData locking the row for the rest of the
+	// transaction, to read an example before updating it.
+	GetDataForUpdate(ctx context.Context, id uint64) (*pb.ExampleORM, error)
+	// ListData returns a page of the examples that are not deleted.
+	ListData(ctx context.Context, opts ListOptions) ([]*pb.ExampleORM, PageInfo, error)
+	// UpdateData saves the fields of an existing example, codes.NotFound if
+	// it is missing or deleted.
+	UpdateData(ctx context.Context, data *pb.ExampleORM) (*pb.ExampleORM, error)
+	// DeleteData marks an example as deleted, codes.NotFound if it is
+	// missing or already deleted.
+	DeleteData(ctx context.Context, id uint64) error
+
+	CreateAPIKey(ctx context.Context, data *pb.ApiKeyORM) (*pb.ApiKeyORM, error)
+	// ListAPIKeys returns every API key, revoked ones included, by id.
+	ListAPIKeys(ctx context.Context) ([]*pb.ApiKeyORM, error)
+	// RevokeAPIKey returns codes.NotFound for a missing or revoked key.
+	RevokeAPIKey(ctx context.Context, id uint64) error
+}
+
+var (
+	_ Repository = (*GormProvider)(nil)
+	_ Repository = (*Fake)(nil)
+)
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+)
+
+const (
+	// maxTxAttempts is how many times a transaction is run when it keeps
+	// failing on serialization failures or deadlocks.
+	maxTxAttempts = 3
+	// txRetryBackoff is the base delay before running a transaction again,
+	// doubled on every attempt.
+	txRetryBackoff = 20 * time.Millisecond
+)
+
+// txKey is the context key of the current transaction.
+type txKey struct{}
+
+// txState is the transaction carried by a context, and how many WithTx
+// calls deep it is, naming the savepoints of nested calls.
+type txState struct {
+	db    *gorm.DB
+	depth int
+}
+
+// WithTx runs fn as a unit of work. Every provider method called with the
+// context passed to fn joins the same transaction, which is committed when
+// fn returns nil and rolled back when it returns an error or panics.
+//
+// A WithTx call inside fn runs in a savepoint instead, so a failed nested
+// unit is rolled back alone and the outer fn may still carry on. The whole
+// transaction is run again, up to maxTxAttempts times, when it fails on a
+// serialization failure or deadlock, so fn must not have side effects
+// outside the database. It stops as soon as ctx is done.
+func (p *GormProvider) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return p.savepoint(ctx, state, fn)
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if cerr := ctx.Err(); cerr != nil {
+			return apperr.FromDB(ctx, cerr)
+		}
+		err = p.runTx(ctx, fn)
+		if err == nil || !apperr.Retryable(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		backoff := txRetryBackoff << (attempt - 1)
+		backoff += time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-ctx.Done():
+			return apperr.FromDB(ctx, ctx.Err())
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (p *GormProvider) runTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx := p.db_main.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return apperr.FromDB(ctx, tx.Error)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, &txState{db: tx})); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return apperr.FromDB(ctx, err)
+	}
+
+	return nil
+}
+
+func (p *GormProvider) savepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) (err error) {
+	nested := &txState{db: state.db, depth: state.depth + 1}
+	name := fmt.Sprintf("sp%d", nested.depth)
+	if err := state.db.WithContext(ctx).SavePoint(name).Error; err != nil {
+		return apperr.FromDB(ctx, err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			state.db.WithContext(ctx).RollbackTo(name)
+			panic(r)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, nested)); err != nil {
+		if rerr := state.db.WithContext(ctx).RollbackTo(name).Error; rerr != nil {
+			return apperr.FromDB(ctx, rerr)
+		}
+		return err
+	}
+	if err := state.db.WithContext(ctx).Exec("RELEASE SAVEPOINT " + name).Error; err != nil {
+		return apperr.FromDB(ctx, err)
+	}
+
+	return nil
+}
+
+// conn returns the database to query with ctx: the transaction of WithTx
+// when ctx carries one, else the main database.
+func (p *GormProvider) conn(ctx context.Context) *gorm.DB {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.db.WithContext(ctx)
+	}
+
+	return p.db_main.WithContext(ctx)
+}
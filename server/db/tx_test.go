@@ -0,0 +1,232 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+	"github.com/sandisuryadi36/micro-svc-template/server/db"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+// sqlLog records the statements run by gorm.
+type sqlLog struct {
+	mu    sync.Mutex
+	stmts []string
+}
+
+func (l *sqlLog) LogMode(logger.LogLevel) logger.Interface      { return l }
+func (l *sqlLog) Info(context.Context, string, ...interface{})  {}
+func (l *sqlLog) Warn(context.Context, string, ...interface{})  {}
+func (l *sqlLog) Error(context.Context, string, ...interface{}) {}
+func (l *sqlLog) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stmts = append(l.stmts, sql)
+}
+
+func (l *sqlLog) contains(stmt string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, s := range l.stmts {
+		if strings.EqualFold(s, stmt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// openSQLite returns an in-memory SQLite database, kept on a single
+// connection as it only lives as long as its connection.
+func openSQLite(t *testing.T, log logger.Interface) *gorm.DB {
+	t.Helper()
+	if log == nil {
+		log = logger.Discard
+	}
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: log})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return gdb
+}
+
+// openExamples returns a SQLite database holding the examples table.
+func openExamples(t *testing.T, log logger.Interface) *gorm.DB {
+	t.Helper()
+	gdb := openSQLite(t, log)
+	err := gdb.Exec(`CREATE TABLE example_table (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		crated_at DATETIME,
+		updated_at DATETIME,
+		deleted_at DATETIME
+	)`).Error
+	if err != nil {
+		t.Fatalf("create example_table: %v", err)
+	}
+
+	return gdb
+}
+
+func names(t *testing.T, gdb *gorm.DB) string {
+	t.Helper()
+	var names []string
+	if err := gdb.Model(&pb.ExampleORM{}).Order("id").Pluck("name", &names).Error; err != nil {
+		t.Fatalf("names: %v", err)
+	}
+
+	return strings.Join(names, ",")
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	gdb := openExamples(t, nil)
+	p := db.NewProvider(gdb, nil)
+	create := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			_, err := p.CreateData(ctx, &pb.ExampleORM{Name: name})
+			return err
+		}
+	}
+
+	if err := p.WithTx(ctx, create("committed")); err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	errFail := errors.New("fail")
+	err := p.WithTx(ctx, func(ctx context.Context) error {
+		if err := create("rolled back")(ctx); err != nil {
+			return err
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Errorf("WithTx = %v, want %v", err, errFail)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the panic of fn", r)
+			}
+		}()
+		p.WithTx(ctx, func(ctx context.Context) error {
+			create("panicked")(ctx)
+			panic("boom")
+		})
+	}()
+
+	if got := names(t, gdb); got != "committed" {
+		t.Errorf("examples = %s, want only committed", got)
+	}
+}
+
+func TestWithTxSavepoints(t *testing.T) {
+	ctx := context.Background()
+	log := &sqlLog{}
+	gdb := openExamples(t, log)
+	p := db.NewProvider(gdb, nil)
+	create := func(ctx context.Context, name string) error {
+		_, err := p.CreateData(ctx, &pb.ExampleORM{Name: name})
+		return err
+	}
+
+	errFail := errors.New("fail")
+	err := p.WithTx(ctx, func(ctx context.Context) error {
+		if err := create(ctx, "outer"); err != nil {
+			return err
+		}
+		err := p.WithTx(ctx, func(ctx context.Context) error {
+			if err := create(ctx, "failed"); err != nil {
+				return err
+			}
+			return errFail
+		})
+		if !errors.Is(err, errFail) {
+			t.Errorf("nested WithTx = %v, want %v", err, errFail)
+		}
+		return p.WithTx(ctx, func(ctx context.Context) error {
+			if err := create(ctx, "nested"); err != nil {
+				return err
+			}
+			return p.WithTx(ctx, func(ctx context.Context) error {
+				return create(ctx, "deepest")
+			})
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	if got := names(t, gdb); got != "outer,nested,deepest" {
+		t.Errorf("examples = %s, want outer,nested,deepest", got)
+	}
+	for _, stmt := range []string{
+		"SAVEPOINT sp1",
+		"ROLLBACK TO SAVEPOINT sp1",
+		"SAVEPOINT sp2",
+		"RELEASE SAVEPOINT sp2",
+		"RELEASE SAVEPOINT sp1",
+	} {
+		if !log.contains(stmt) {
+			t.Errorf("%s not run", stmt)
+		}
+	}
+}
+
+func TestWithTxRetries(t *testing.T) {
+	p := db.NewProvider(openExamples(t, nil), nil)
+
+	tests := []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{name: "serialization failure", err: apperr.Aborted("conflict"), calls: 3},
+		{name: "other error", err: errors.New("fail"), calls: 1},
+		{name: "success", calls: 1},
+	}
+	for _, tt := range tests {
+		calls := 0
+		err := p.WithTx(context.Background(), func(ctx context.Context) error {
+			calls++
+			return tt.err
+		})
+		if !errors.Is(err, tt.err) && status.Code(err) != status.Code(tt.err) {
+			t.Errorf("%s: WithTx = %v, want %v", tt.name, err, tt.err)
+		}
+		if calls != tt.calls {
+			t.Errorf("%s: fn called %d times, want %d", tt.name, calls, tt.calls)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := p.WithTx(ctx, func(ctx context.Context) error {
+		t.Errorf("fn called with a canceled context")
+		return nil
+	})
+	if code := status.Code(err); code != codes.Canceled {
+		t.Errorf("WithTx with a canceled context code = %v, want Canceled", code)
+	}
+}
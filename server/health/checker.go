@@ -0,0 +1,171 @@
+// Package health tracks the readiness of the service from named checks and
+// reports it through the grpc.health.v1.Health service and the /healthz and
+// /readyz HTTP routes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check reports a problem with a dependency by returning an error.
+type Check func(ctx context.Context) error
+
+// Report is the result of running every check.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+// Checker runs the registered readiness checks.
+type Checker struct {
+	mu       sync.RWMutex
+	checks   map[string]Check
+	timeout  time.Duration
+	services []string
+
+	grpc *health.Server
+}
+
+// NewChecker returns a Checker that gives all checks together at most
+// timeout. services are the gRPC service names whose serving status follows
+// the readiness of the service, in addition to the overall "" service.
+func NewChecker(timeout time.Duration, services ...string) *Checker {
+	c := &Checker{
+		checks:   map[string]Check{},
+		timeout:  timeout,
+		services: append([]string{""}, services...),
+		grpc:     health.NewServer(),
+	}
+	c.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return c
+}
+
+// Register adds a named readiness check, replacing any check with the same
+// name.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// GRPCServer returns the grpc.health.v1.Health implementation to register on
+// the gRPC server.
+func (c *Checker) GRPCServer() healthpb.HealthServer {
+	return c.grpc
+}
+
+// Check runs every registered check concurrently.
+func (c *Checker) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: statusOK, Checks: map[string]string{}}
+	for i, name := range names {
+		report.Checks[name] = statusOK
+		if results[i] != nil {
+			report.Checks[name] = results[i].Error()
+			report.Status = statusUnavailable
+		}
+	}
+
+	return report
+}
+
+// Watch re-evaluates the checks every interval and updates the gRPC serving
+// status until ctx is done.
+func (c *Checker) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.update(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown permanently reports NOT_SERVING to gRPC health clients.
+func (c *Checker) Shutdown(ctx context.Context) error {
+	c.grpc.Shutdown()
+	return nil
+}
+
+func (c *Checker) update(ctx context.Context) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if c.Check(ctx).Status != statusOK {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	c.setServingStatus(status)
+}
+
+func (c *Checker) setServingStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range c.services {
+		c.grpc.SetServingStatus(service, status)
+	}
+}
+
+// HandleLiveness serves /healthz. It only reports that the process is able
+// to serve HTTP, dependencies are covered by readiness.
+func (c *Checker) HandleLiveness(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	writeReport(w, http.StatusOK, Report{Status: statusOK})
+}
+
+// HandleReadiness serves /readyz with the result of every check.
+func (c *Checker) HandleReadiness(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	report := c.Check(r.Context())
+
+	code := http.StatusOK
+	if report.Status != statusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeReport(w, code, report)
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,91 @@
+// Package logging sets up the structured logger of the service and carries
+// request-scoped fields in the context, so every log line written with a
+// request context (slog.InfoContext and friends) shares the same
+// correlation fields.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+// Setup builds the logger for the given format ("json" or "text") and level
+// and installs it as the slog default. Output of the standard log package is
+// routed through it as well.
+func Setup(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+
+	logger := slog.New(&contextHandler{handler})
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+
+	return logger, nil
+}
+
+// With returns a copy of ctx carrying attrs in addition to the fields it
+// already has.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := Attrs(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+// Attrs returns the fields carried by ctx.
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+
+	return attrs
+}
+
+// contextHandler adds the fields carried by the record's context, and the
+// trace and span IDs of the span it holds.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(Attrs(ctx)...)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
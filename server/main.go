@@ -2,37 +2,145 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
-	"github.com/joho/godotenv"
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
 	"github.com/sandisuryadi36/micro-svc-template/server/api"
+	"github.com/sandisuryadi36/micro-svc-template/server/auth"
+	"github.com/sandisuryadi36/micro-svc-template/server/config"
+	"github.com/sandisuryadi36/micro-svc-template/server/db"
+	"github.com/sandisuryadi36/micro-svc-template/server/health"
+	"github.com/sandisuryadi36/micro-svc-template/server/logging"
+	"github.com/sandisuryadi36/micro-svc-template/server/metrics"
 	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+	"github.com/sandisuryadi36/micro-svc-template/server/ratelimit"
+	"github.com/sandisuryadi36/micro-svc-template/server/shutdown"
+	"github.com/sandisuryadi36/micro-svc-template/server/tlsutil"
+	"github.com/sandisuryadi36/micro-svc-template/server/tracing"
 )
 
 func main() {
+	// Load configuration once for the whole process
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Structured logger for the whole process
+	if _, err := logging.Setup(os.Stderr, cfg.Log.Format, cfg.Log.Level); err != nil {
+		log.Fatalf("Failed to set up logger: %v", err)
+	}
+
+	// Run "migrate" subcommand instead of the server when requested
+	if len(args) > 0 && args[0] == "migrate" {
+		os.Exit(runMigrateCommand(cfg, args[1:]))
+	}
+
+	// Registry of hooks run in order on shutdown
+	shutdowns := shutdown.NewRegistry()
+
+	// Initiate OpenTelemetry tracing
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	// start DB connection
+	startDBConnection(cfg.DB)
+
 	// migrate DB
 	migrateDB()
 
-	// start DB connection
-	startDBConnection()
+	// Initiate Prometheus metrics
+	metricsRec := metrics.New()
+	registerMetrics(metricsRec)
+
+	provider := db.NewProvider(dbMain, dbReplicas)
+
+	// Initiate JWT verification when authentication is enabled
+	var verifier *auth.Verifier
+	if cfg.Auth.Enabled {
+		verifier, err = auth.NewVerifier(context.Background(), cfg.Auth, provider)
+		if err != nil {
+			log.Fatalf("Failed to set up authentication: %v", err)
+		}
+	}
+
+	// Initiate rate limiting when enabled
+	var limiter *ratelimit.Limiter
+	var httpRule, preAuthRule ratelimit.Rule
+	overrides := map[string]ratelimit.Rule{}
+	for _, m := range cfg.RateLimit.Methods {
+		overrides[m.Method] = ratelimit.Rule{Rate: m.RPS, Burst: m.Burst}
+	}
+	rateRules := ratelimit.NewRules(ratelimit.Rule{Rate: cfg.RateLimit.RPS, Burst: cfg.RateLimit.Burst}, overrides)
+	proxies, err := ratelimit.ParseProxies(cfg.RateLimit.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to set up rate limiting: %v", err)
+	}
+	if cfg.RateLimit.Enabled {
+		limiter = ratelimit.New()
+		httpRule = ratelimit.Rule{Rate: cfg.RateLimit.HTTPRPS, Burst: cfg.RateLimit.HTTPBurst}
+		preAuthRule = ratelimit.Rule{Rate: cfg.RateLimit.PreAuthRPS, Burst: cfg.RateLimit.PreAuthBurst}
+	}
+
+	// Load TLS certificates when enabled
+	tlsFiles := setupTLS(cfg)
 
 	// Initiate gRPC server
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		requestIDMiddleware,
+		recoveryMiddleware,
+		tracingMiddleware,
+		loggingMiddleware,
+		metricsRec.UnaryInterceptor,
+		preAuthRateLimitMiddleware(limiter, preAuthRule, proxies),
+		authMiddleware(verifier),
+		rateLimitMiddleware(limiter, rateRules, proxies),
+		authorizationMiddleware,
+		validationMiddleware,
+	}
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingMiddleware),
+		grpc.Creds(tlsFiles.grpcCreds),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(
+			recoveryStreamMiddleware,
+			tracingStreamMiddleware,
+			metricsRec.StreamInterceptor,
+			preAuthRateLimitStreamMiddleware(limiter, preAuthRule, proxies),
+			authStreamMiddleware(verifier),
+			rateLimitStreamMiddleware(limiter, rateRules, proxies),
+			authorizationStreamMiddleware,
+			validationStreamMiddleware,
+		),
 	)
 
 	apiServ := api.New(
-		dbMain,
+		cfg,
+		provider,
 	)
 	// Register handler to gRPC server
 	pb.RegisterApiServiceServer(grpcServer, apiServ)
@@ -40,63 +148,293 @@ func main() {
 	// Register reflection service for debugging
 	reflection.Register(grpcServer)
 
+	// Initiate readiness checks and register gRPC health service
+	checker := health.NewChecker(cfg.Health.Timeout, pb.ApiService_ServiceDesc.ServiceName)
+	registerHealthChecks(checker, shutdowns)
+	healthpb.RegisterHealthServer(grpcServer, checker.GRPCServer())
+
 	// Initiate listener for HTTP gateway
-	httpListener, err := net.Listen("tcp", ":8080")
+	httpListener, err := net.Listen("tcp", cfg.HTTP.Addr)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
+	if tlsFiles.http != nil {
+		httpListener = tls.NewListener(httpListener, tlsFiles.http)
+	}
 
 	// Initiate gRPC-gateway Mux
-	gwMux := runtime.NewServeMux()
+	gwMux := runtime.NewServeMux(
+		runtime.WithMetadata(requestIDAnnotator),
+		runtime.WithMetadata(tracingAnnotator),
+		runtime.WithMetadata(metrics.RouteAnnotator),
+		runtime.WithErrorHandler(httpErrorHandler),
+		runtime.WithIncomingHeaderMatcher(httpHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(httpOutgoingHeaderMatcher),
+	)
 
-	// Register HTTP handler for gRPC service
-	err = pb.RegisterApiServiceHandlerFromEndpoint(context.Background(), gwMux, fmt.Sprintf("localhost:%d", 9090), []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())})
+	// Register liveness and readiness routes
+	if err := gwMux.HandlePath(http.MethodGet, "/healthz", metrics.WithRoute("/healthz", checker.HandleLiveness)); err != nil {
+		log.Fatalf("Failed to register liveness route: %v", err)
+	}
+	if err := gwMux.HandlePath(http.MethodGet, "/readyz", metrics.WithRoute("/readyz", checker.HandleReadiness)); err != nil {
+		log.Fatalf("Failed to register readiness route: %v", err)
+	}
+
+	// Register HTTP handler for gRPC service, in-process on a single port or
+	// through a connection to the gRPC listener
+	if cfg.HTTP.SinglePort {
+		err = pb.RegisterApiServiceHandlerServer(context.Background(), gwMux, api.NewLocal(apiServ, chainUnaryInterceptors(unaryInterceptors)))
+	} else {
+		err = pb.RegisterApiServiceHandlerFromEndpoint(context.Background(), gwMux, cfg.GRPC.GatewayEndpoint, []grpc.DialOption{grpc.WithTransportCredentials(tlsFiles.gatewayCreds)})
+	}
 	if err != nil {
 		log.Fatalf("Failed to register HTTP gateway: %v", err)
 	}
 
 	// Initiate HTTP server
+	var httpHandler http.Handler = requestIDHTTPMiddleware(tracingHTTPMiddleware(loggingHTTPMiddleware(metricsRec.HTTPMiddleware(rateLimitHTTPMiddleware(limiter, httpRule, proxies)(recoveryHTTPMiddleware(gwMux))))))
+	if cfg.HTTP.SinglePort {
+		httpHandler = singlePortHandler(grpcServer, httpHandler, cfg.HTTP.GRPCWebOrigins, !cfg.TLS.Enabled)
+	}
 	httpServer := &http.Server{
-		Addr:    ":8080",
-		Handler: loggingHTTPMiddleware(gwMux),
+		Addr:    cfg.HTTP.Addr,
+		Handler: httpHandler,
+	}
+
+	// Initiate admin server for operational endpoints
+	adminListener, err := net.Listen("tcp", cfg.Admin.Addr)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", metricsRec.Handler())
+	adminServer := &http.Server{
+		Addr:    cfg.Admin.Addr,
+		Handler: adminMux,
+	}
+
+	// Initiate listener for gRPC server, unless it shares the HTTP port
+	var grpcListener net.Listener
+	if !cfg.HTTP.SinglePort {
+		grpcListener, err = net.Listen("tcp", cfg.GRPC.Addr)
+		if err != nil {
+			log.Fatalf("Failed to listen: %v", err)
+		}
+	}
+
+	// Register shutdown hooks, servers are drained before the DB is closed
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	shutdowns.Register("health", shutdown.PhaseNotReady, func(ctx context.Context) error {
+		stopWatch()
+		return checker.Shutdown(ctx)
+	})
+	shutdowns.Register("gRPC server", shutdown.PhaseServers, func(ctx context.Context) error {
+		return stopGRPCServer(ctx, grpcServer)
+	})
+	shutdowns.Register("HTTP server", shutdown.PhaseServers, httpServer.Shutdown)
+	shutdowns.Register("admin server", shutdown.PhaseServers, adminServer.Shutdown)
+	shutdowns.Register("DB main", shutdown.PhaseResources, func(ctx context.Context) error {
+		return closeDBMain()
+	})
+	shutdowns.Register("DB replicas", shutdown.PhaseResources, func(ctx context.Context) error {
+		return closeDBReplicas()
+	})
+	shutdowns.Register("tracing", shutdown.PhaseResources, shutdownTracing)
+
+	// Keep gRPC health status in sync with the readiness checks
+	go checker.Watch(watchCtx, cfg.Health.Interval)
+
+	// Watch the DB main connection, readiness reports it while it is lost
+	go dbMainMonitor.Watch(watchCtx, cfg.Health.Interval, cfg.Health.Timeout)
+
+	// Take unreachable read replicas out of rotation until they recover
+	if dbReplicas != nil {
+		go dbReplicas.Watch(watchCtx, cfg.Health.Interval, cfg.Health.Timeout)
+	}
+
+	// Pick up rotated certificates without a restart
+	for _, r := range tlsFiles.reloaders {
+		go r.Watch(watchCtx, cfg.TLS.ReloadInterval)
 	}
 
 	// Start server gRPC and HTTP API
+	serveErr := make(chan error, 3)
+	if grpcListener != nil {
+		go func() {
+			log.Printf("Starting gRPC server on %s", cfg.GRPC.Addr)
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				serveErr <- fmt.Errorf("failed to serve gRPC server: %w", err)
+			}
+		}()
+	}
+
 	go func() {
-		log.Printf("Starting gRPC server on localhost:9090")
-		lis, err := net.Listen("tcp", ":9090")
-		if err != nil {
-			log.Fatalf("failed to listen: %v", err)
+		if cfg.HTTP.SinglePort {
+			log.Printf("Starting HTTP server with gRPC and gRPC-Web on %s", cfg.HTTP.Addr)
+		} else {
+			log.Printf("Starting HTTP server on %s", cfg.HTTP.Addr)
 		}
-		err = grpcServer.Serve(lis)
-		if err != nil {
-			log.Fatalf("Failed to serve gRPC server: %v", err)
+		if err := httpServer.Serve(httpListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("failed to serve HTTP server: %w", err)
 		}
 	}()
 
 	go func() {
-		log.Printf("Starting HTTP server on localhost:8080")
-		err = httpServer.Serve(httpListener)
-		if err != nil {
-			log.Fatalf("Failed to serve HTTP server: %v", err)
+		log.Printf("Starting admin server on %s", cfg.Admin.Addr)
+		if err := adminServer.Serve(adminListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("failed to serve admin server: %w", err)
 		}
 	}()
 
-	// Wait for Control C to exit
-	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, os.Interrupt)
-	// Block until a signal is received
-	<-ch
+	// Block until SIGINT/SIGTERM is received or a server stops on its own
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		log.Printf("Shutdown signal received, draining for at most %s", cfg.Shutdown.Timeout)
+	case err := <-serveErr:
+		log.Printf("Server stopped unexpectedly: %v", err)
+		exitCode = 1
+	}
+	// A second signal kills the process immediately
+	stop()
+
+	if err := shutdowns.Shutdown(cfg.Shutdown.Timeout); err != nil {
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// tlsSetup holds the transport security of the listeners and of the
+// gateway's dial, plaintext unless TLS is enabled.
+type tlsSetup struct {
+	grpcCreds    credentials.TransportCredentials
+	gatewayCreds credentials.TransportCredentials
+	http         *tls.Config
+	reloaders    []*tlsutil.Reloader
+}
+
+// setupTLS loads the certificates configured in cfg.TLS.
+func setupTLS(cfg *config.Config) tlsSetup {
+	if !cfg.TLS.Enabled {
+		return tlsSetup{
+			grpcCreds:    insecure.NewCredentials(),
+			gatewayCreds: insecure.NewCredentials(),
+		}
+	}
+
+	server, err := tlsutil.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile)
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificate: %v", err)
+	}
+
+	// The gateway presents the server certificate unless it has its own
+	clientCert, clientKey := cfg.TLS.CertFile, cfg.TLS.KeyFile
+	if cfg.TLS.ClientCertFile != "" {
+		clientCert, clientKey = cfg.TLS.ClientCertFile, cfg.TLS.ClientKeyFile
+	}
+	client, err := tlsutil.NewReloader(clientCert, clientKey, cfg.TLS.CAFile)
+	if err != nil {
+		log.Fatalf("Failed to load TLS client certificate: %v", err)
+	}
+
+	serverName := cfg.TLS.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(cfg.GRPC.GatewayEndpoint)
+	}
+
+	return tlsSetup{
+		grpcCreds:    credentials.NewTLS(server.ServerConfig(cfg.TLS.ClientCAFile != "")),
+		gatewayCreds: credentials.NewTLS(client.ClientConfig(serverName)),
+		http:         server.ServerConfig(cfg.TLS.HTTPClientAuth),
+		reloaders:    []*tlsutil.Reloader{server, client},
+	}
+}
+
+// registerHealthChecks adds the readiness checks of the components set up in main.
+func registerHealthChecks(checker *health.Checker, shutdowns *shutdown.Registry) {
+	checker.Register("db", dbMainMonitor.Err)
+	checker.Register("migrations", func(ctx context.Context) error {
+		if !dbMigrated.Load() {
+			return errors.New("migrations not applied")
+		}
+		return nil
+	})
+	checker.Register("shutdown", func(ctx context.Context) error {
+		if shutdowns.ShuttingDown() {
+			return errors.New("shutting down")
+		}
+		return nil
+	})
+}
 
-	closeDBMain()
+// registerMetrics adds the metrics of the components set up in main.
+func registerMetrics(m *metrics.Metrics) {
+	if err := m.RegisterDB("main", dbMainSQL); err != nil {
+		log.Fatalf("Failed to register DB metrics: %v", err)
+	}
+	for i, replicaSQL := range dbReplicasSQL {
+		if err := m.RegisterDB(fmt.Sprintf("replica_%d", i+1), replicaSQL); err != nil {
+			log.Fatalf("Failed to register DB metrics: %v", err)
+		}
+	}
+	err := m.Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "recovered_panics_total",
+		Help: "Total number of panics recovered by the gRPC and HTTP middlewares.",
+	}, func() float64 {
+		return float64(recoveredPanics.Load())
+	}))
+	if err != nil {
+		log.Fatalf("Failed to register panic metrics: %v", err)
+	}
 }
 
-// GetEnv return environment variable from .env file. return fallback string if not found
-func GetEnv(key, fallback string) string {
-	godotenv.Load(".env")
-	if value, ok := os.LookupEnv(key); ok {
-		return value
+// singlePortHandler routes native gRPC and gRPC-Web requests to the gRPC
+// server and everything else to the REST gateway. cleartext accepts HTTP/2
+// without TLS (h2c), which native gRPC clients use on plaintext ports.
+func singlePortHandler(grpcServer *grpc.Server, gateway http.Handler, webOrigins []string, cleartext bool) http.Handler {
+	web := grpcweb.WrapServer(grpcServer, grpcweb.WithOriginFunc(func(origin string) bool {
+		for _, allowed := range webOrigins {
+			if allowed == "*" || allowed == origin {
+				return true
+			}
+		}
+		return false
+	}))
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case web.IsGrpcWebRequest(r) || web.IsAcceptableGrpcCorsRequest(r):
+			web.ServeHTTP(w, r)
+		case r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc"):
+			grpcServer.ServeHTTP(w, r)
+		default:
+			gateway.ServeHTTP(w, r)
+		}
+	})
+	if cleartext {
+		handler = h2c.NewHandler(handler, &http2.Server{})
 	}
-	return fallback
+
+	return handler
 }
 
+// stopGRPCServer waits for in-flight RPCs to finish, or force closes every
+// connection once ctx is done.
+func stopGRPCServer(ctx context.Context, server *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,193 @@
+// Package metrics exposes Prometheus metrics for the gRPC server, the HTTP
+// gateway routes and database connection pools.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics holds the collectors of the service and the registry they are
+// exposed from.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	grpcStarted  *prometheus.CounterVec
+	grpcHandled  *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+}
+
+// New creates the collectors, along with the Go runtime and process ones, in
+// a dedicated registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		grpcStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_started_total",
+			Help: "Total number of RPCs started on the server.",
+		}, []string{"grpc_type", "grpc_service", "grpc_method"}),
+		grpcHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Total number of RPCs completed on the server, regardless of success or failure.",
+		}, []string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Histogram of response latency of RPCs handled by the server.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"grpc_type", "grpc_service", "grpc_method"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Histogram of HTTP request latency by route and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.grpcStarted,
+		m.grpcHandled,
+		m.grpcDuration,
+		m.httpRequests,
+		m.httpDuration,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Register adds extra collectors to the registry.
+func (m *Metrics) Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := m.registry.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterDB exposes the sql.DBStats of db (open, in use and idle
+// connections, wait count and duration) labelled with name.
+func (m *Metrics) RegisterDB(name string, db *sql.DB) error {
+	return m.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// UnaryInterceptor records request count, latency and status code of unary RPCs.
+func (m *Metrics) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	done := m.startRPC("unary", info.FullMethod)
+	resp, err := handler(ctx, req)
+	done(err)
+
+	return resp, err
+}
+
+// StreamInterceptor records request count, latency and status code of streaming RPCs.
+func (m *Metrics) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	rpcType := "bidi_stream"
+	switch {
+	case info.IsClientStream && !info.IsServerStream:
+		rpcType = "client_stream"
+	case !info.IsClientStream && info.IsServerStream:
+		rpcType = "server_stream"
+	}
+
+	done := m.startRPC(rpcType, info.FullMethod)
+	err := handler(srv, ss)
+	done(err)
+
+	return err
+}
+
+func (m *Metrics) startRPC(rpcType, fullMethod string) func(error) {
+	service, method := splitMethodName(fullMethod)
+	m.grpcStarted.WithLabelValues(rpcType, service, method).Inc()
+	start := time.Now()
+
+	return func(err error) {
+		m.grpcHandled.WithLabelValues(rpcType, service, method, status.Code(err).String()).Inc()
+		m.grpcDuration.WithLabelValues(rpcType, service, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// HTTPMiddleware records request count, latency and status code per route.
+// Gateway routes are labelled with their path template (see RouteAnnotator),
+// other routes with the template passed to WithRoute.
+func (m *Metrics) HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := &routeHolder{}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routeKey{}, route)))
+
+		name := route.name
+		if name == "" {
+			name = "unmatched"
+		}
+		m.httpRequests.WithLabelValues(name, r.Method, strconv.Itoa(rec.status)).Inc()
+		m.httpDuration.WithLabelValues(name, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+func splitMethodName(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+
+	return "unknown", fullMethod
+}
+
+// statusRecorder captures the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming responses through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+)
+
+type routeKey struct{}
+
+// routeHolder is placed in the request context by HTTPMiddleware and filled
+// in once the gateway has matched a route, so the route template is known
+// when the request is recorded.
+type routeHolder struct {
+	name string
+}
+
+// SetRoute records the route template of the request served with ctx.
+func SetRoute(ctx context.Context, route string) {
+	if holder, ok := ctx.Value(routeKey{}).(*routeHolder); ok {
+		holder.name = route
+	}
+}
+
+// RouteAnnotator is a gateway metadata annotator (runtime.WithMetadata) that
+// records the path template matched by the gateway, e.g. /api/examples/{id}.
+// It adds no metadata.
+func RouteAnnotator(ctx context.Context, r *http.Request) metadata.MD {
+	if pattern, ok := runtime.HTTPPathPattern(ctx); ok {
+		SetRoute(r.Context(), pattern)
+	}
+
+	return nil
+}
+
+// WithRoute labels the requests served by a handler registered with
+// runtime.ServeMux.HandlePath, which bypasses the annotators.
+func WithRoute(route string, h runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		SetRoute(r.Context(), route)
+		h(w, r, pathParams)
+	}
+}
@@ -2,32 +2,721 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+	"github.com/sandisuryadi36/micro-svc-template/server/auth"
+	"github.com/sandisuryadi36/micro-svc-template/server/logging"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+	"github.com/sandisuryadi36/micro-svc-template/server/ratelimit"
+	"github.com/sandisuryadi36/micro-svc-template/server/requestid"
+	"github.com/sandisuryadi36/micro-svc-template/server/tracing"
+	"github.com/sandisuryadi36/micro-svc-template/server/validate"
 )
 
+// recoveredPanics counts the panics recovered by the recovery middlewares
+var recoveredPanics atomic.Uint64
+
+// Middleware recovery for RPC, turns a panic in a handler into codes.Internal
+func recoveryMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverPanic(ctx, r)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// Middleware recovery for streaming RPC
+func recoveryStreamMiddleware(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverPanic(logging.With(ss.Context(), slog.String("method", info.FullMethod)), r)
+		}
+	}()
+
+	return handler(srv, ss)
+}
+
+// recoverPanic logs a recovered panic with its stack trace and returns the
+// error sent to the client, which leaves the details out
+func recoverPanic(ctx context.Context, r interface{}) error {
+	count := recoveredPanics.Add(1)
+	slog.ErrorContext(ctx, "Recovered from panic",
+		slog.Any("panic", r),
+		slog.String("stack", string(debug.Stack())),
+		slog.Uint64("recovered_panics", count),
+	)
+
+	return status.Error(codes.Internal, "Internal Error")
+}
+
+// chainUnaryInterceptors combines interceptors into one, the first being the
+// outermost, as grpc.ChainUnaryInterceptor does for the gRPC server
+func chainUnaryInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+
+		return chained(ctx, req)
+	}
+}
+
+// Middleware request ID for RPC, reuses the ID sent by the caller (or the
+// gateway) and echoes it back in the response header and trailer
+func requestIDMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestid.MetadataKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	id = requestid.Ensure(id)
+	ctx = requestid.NewContext(ctx, id)
+
+	md := metadata.Pairs(requestid.MetadataKey, id)
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		slog.WarnContext(ctx, "Failed to set request ID header", slog.String("error", err.Error()))
+	}
+	resp, err := handler(ctx, req)
+	grpc.SetTrailer(ctx, md)
+
+	return resp, err
+}
+
+// Middleware tracing for RPC, continues the trace sent in the traceparent
+// metadata (by the gateway or another service) with a server span
+func tracingMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := startRPCSpan(ctx, info.FullMethod)
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+	endRPCSpan(span, err)
+
+	return resp, err
+}
+
+// Middleware tracing for streaming RPC
+func tracingStreamMiddleware(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := startRPCSpan(ss.Context(), info.FullMethod)
+	defer span.End()
+
+	err := handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+	endRPCSpan(span, err)
+
+	return err
+}
+
+func startRPCSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = tracing.Extract(ctx, md)
+	}
+
+	name := strings.TrimPrefix(fullMethod, "/")
+	service, method, _ := strings.Cut(name, "/")
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(method),
+		),
+	)
+}
+
+// endRPCSpan records the status of an RPC, only server side failures mark
+// the span as an error
+func endRPCSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+}
+
+// tracedStream carries the context built by the middlewares, such as the
+// span or the claims, of a streaming RPC.
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
+
+// Middleware authentication for RPC, requires a valid bearer token or API
+// key unless the method is declared public. A nil verifier disables authentication.
+func authMiddleware(verifier *auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, verifier, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Middleware authentication for streaming RPC
+func authStreamMiddleware(verifier *auth.Verifier) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), verifier, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate verifies the API key or bearer token of the call and returns
+// ctx with its claims
+func authenticate(ctx context.Context, verifier *auth.Verifier, fullMethod string) (context.Context, error) {
+	if verifier == nil || auth.Policy(fullMethod).GetPublic() {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var claims *auth.Claims
+	if key := auth.APIKey(md); key != "" {
+		var err error
+		claims, err = verifier.VerifyAPIKey(ctx, key)
+		if err != nil {
+			if s, ok := status.FromError(err); ok {
+				return ctx, s.Err()
+			}
+			return ctx, apperr.Unauthenticated("%v", err)
+		}
+	} else {
+		token, err := auth.BearerToken(md)
+		if err != nil {
+			return ctx, apperr.Unauthenticated("%v", err)
+		}
+		claims, err = verifier.Verify(ctx, token)
+		if err != nil {
+			return ctx, apperr.Unauthenticated("%v", err)
+		}
+	}
+
+	ctx = auth.NewContext(ctx, claims)
+	return logging.With(ctx, slog.String("subject", claims.Subject)), nil
+}
+
+// Middleware authorization for RPC, enforces the roles and permissions of
+// the method's auth option on the authenticated caller
+func authorizationMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// Middleware authorization for streaming RPC
+func authorizationStreamMiddleware(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+// authorize denies the call when the caller lacks a required role or
+// permission, and writes an audit log line for every denial. Calls without
+// claims (public methods or authentication disabled) are let through.
+func authorize(ctx context.Context, fullMethod string) error {
+	claims := auth.FromContext(ctx)
+	if claims == nil {
+		return nil
+	}
+
+	policy := auth.Policy(fullMethod)
+	if err := auth.Authorize(policy, claims); err != nil {
+		slog.WarnContext(ctx, "Audit: permission denied",
+			slog.String("audit", "permission_denied"),
+			slog.String("method", fullMethod),
+			slog.Any("roles", claims.Roles),
+			slog.Any("required_roles", policy.GetRoles()),
+			slog.Any("required_permissions", policy.GetPermissions()),
+			slog.String("reason", err.Error()),
+		)
+		return apperr.PermissionDenied("%v", err)
+	}
+
+	return nil
+}
+
+// Middleware validation for RPC, checks the request against the (rules)
+// options of its fields before the handler runs
+func validationMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// Middleware validation for streaming RPC, checks every received message
+func validationStreamMiddleware(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &validatedStream{ServerStream: ss})
+}
+
+// validatedStream validates the messages of a streaming RPC as they are
+// received.
+type validatedStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return validateRequest(m)
+}
+
+func validateRequest(req interface{}) error {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	if violations := validate.Message(msg); len(violations) > 0 {
+		return apperr.InvalidArgument("Invalid request", violations...)
+	}
+
+	return nil
+}
+
+// Middleware rate limit for RPC, takes a token from the caller's bucket for
+// the method and answers codes.ResourceExhausted when it is empty. A nil
+// limiter disables rate limiting.
+func rateLimitMiddleware(limiter *ratelimit.Limiter, rules *ratelimit.Rules, proxies ratelimit.Proxies) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := rateLimit(ctx, limiter, rules, proxies, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Middleware rate limit for streaming RPC, counts the stream as one request
+func rateLimitStreamMiddleware(limiter *ratelimit.Limiter, rules *ratelimit.Rules, proxies ratelimit.Proxies) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := rateLimit(ss.Context(), limiter, rules, proxies, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// Middleware pre-auth rate limit for RPC, takes a token from the bucket of
+// the client IP before authentication runs, so that floods of calls failing
+// authentication and API key guessing are limited as well. A nil limiter or
+// a disabled rule turns it off.
+func preAuthRateLimitMiddleware(limiter *ratelimit.Limiter, rule ratelimit.Rule, proxies ratelimit.Proxies) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := preAuthRateLimit(ctx, limiter, rule, proxies); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Middleware pre-auth rate limit for streaming RPC
+func preAuthRateLimitStreamMiddleware(limiter *ratelimit.Limiter, rule ratelimit.Rule, proxies ratelimit.Proxies) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := preAuthRateLimit(ss.Context(), limiter, rule, proxies); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// preAuthRateLimit applies rule to the client IP across all methods. Only a
+// rejection reports its bucket, the headers of an allowed call are left to
+// the per method limit.
+func preAuthRateLimit(ctx context.Context, limiter *ratelimit.Limiter, rule ratelimit.Rule, proxies ratelimit.Proxies) error {
+	if limiter == nil || rule.Disabled() {
+		return nil
+	}
+
+	result := limiter.Allow("preauth|"+rateLimitIP(ctx, proxies), rule)
+	if result.Allowed {
+		return nil
+	}
+	md := metadata.Pairs(
+		ratelimit.LimitKey, strconv.Itoa(result.Limit),
+		ratelimit.RemainingKey, strconv.Itoa(result.Remaining),
+		ratelimit.ResetKey, ratelimit.Seconds(result.Reset),
+		ratelimit.RetryAfterKey, ratelimit.Seconds(result.RetryAfter),
+	)
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		slog.WarnContext(ctx, "Failed to set rate limit header", slog.String("error", err.Error()))
+	}
+
+	return status.Errorf(codes.ResourceExhausted, "Rate limit exceeded, retry in %ss", ratelimit.Seconds(result.RetryAfter))
+}
+
+// rateLimit applies the rule of the method to the caller and reports the
+// state of its bucket in the response header
+func rateLimit(ctx context.Context, limiter *ratelimit.Limiter, rules *ratelimit.Rules, proxies ratelimit.Proxies, fullMethod string) error {
+	if limiter == nil {
+		return nil
+	}
+	rule := rules.For(fullMethod)
+	if rule.Disabled() {
+		return nil
+	}
+
+	result := limiter.Allow(fullMethod+"|"+rateLimitKey(ctx, proxies), rule)
+	md := metadata.Pairs(
+		ratelimit.LimitKey, strconv.Itoa(result.Limit),
+		ratelimit.RemainingKey, strconv.Itoa(result.Remaining),
+		ratelimit.ResetKey, ratelimit.Seconds(result.Reset),
+	)
+	if !result.Allowed {
+		md.Set(ratelimit.RetryAfterKey, ratelimit.Seconds(result.RetryAfter))
+	}
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		slog.WarnContext(ctx, "Failed to set rate limit header", slog.String("error", err.Error()))
+	}
+	if !result.Allowed {
+		return status.Errorf(codes.ResourceExhausted, "Rate limit exceeded, retry in %ss", ratelimit.Seconds(result.RetryAfter))
+	}
+
+	return nil
+}
+
+// rateLimitKey identifies the caller: the subject of its API key or token
+// when authenticated, else its IP address
+func rateLimitKey(ctx context.Context, proxies ratelimit.Proxies) string {
+	if claims := auth.FromContext(ctx); claims != nil && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+
+	return rateLimitIP(ctx, proxies)
+}
+
+// rateLimitIP identifies the caller by its IP address
+func rateLimitIP(ctx context.Context, proxies ratelimit.Proxies) string {
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+	// Calls of the gateway come from loopback or have no peer at all
+	// in-process, the client address is in the forwarded metadata
+	md, _ := metadata.FromIncomingContext(ctx)
+	return "ip:" + ratelimit.ClientIP(addr, md.Get("x-forwarded-for"), proxies)
+}
+
 // Middleware logging for RPC
 func loggingMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	startTime := time.Now()
+
+	fields := []slog.Attr{slog.String("method", info.FullMethod)}
+	if p, ok := peer.FromContext(ctx); ok {
+		fields = append(fields, slog.String("peer", p.Addr.String()))
+	}
+	ctx = logging.With(ctx, fields...)
+
 	resp, err := handler(ctx, req)
 	duration := time.Since(startTime)
 
-	log.Printf("RPC method=%s duration=%s error=%v", info.FullMethod, duration, err)
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("grpc_code", code.String()),
+		slog.Duration("duration", duration),
+	}
+	if msg, ok := resp.(proto.Message); ok && err == nil {
+		attrs = append(attrs, slog.Int("bytes", proto.Size(msg)))
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	slog.LogAttrs(ctx, level, "RPC", attrs...)
 
 	return resp, err
 }
 
+// Middleware request ID for HTTP, accepts X-Request-Id from the client or
+// generates one and echoes it in the response
+func requestIDHTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestid.Ensure(r.Header.Get(requestid.Header))
+		r.Header.Set(requestid.Header, id)
+		w.Header().Set(requestid.Header, id)
+
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
+
+// requestIDAnnotator forwards the request ID of an HTTP request to the gRPC
+// server through metadata
+func requestIDAnnotator(ctx context.Context, r *http.Request) metadata.MD {
+	id := requestid.FromContext(r.Context())
+	if id == "" {
+		return nil
+	}
+
+	return metadata.Pairs(requestid.MetadataKey, id)
+}
+
+// Middleware tracing for HTTP, starts a server span continuing the trace of
+// the W3C traceparent header if the client sent one
+func tracingHTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.ExtractHTTP(r.Context(), r.Header)
+		ctx, span := tracing.Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPStatusCode(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(otelcodes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// tracingAnnotator names the HTTP span after the matched gateway route and
+// propagates the trace to the gRPC server through traceparent metadata
+func tracingAnnotator(ctx context.Context, r *http.Request) metadata.MD {
+	span := trace.SpanFromContext(ctx)
+	if pattern, ok := runtime.HTTPPathPattern(ctx); ok {
+		span.SetName(r.Method + " " + pattern)
+		span.SetAttributes(semconv.HTTPRoute(pattern))
+	}
+
+	return tracing.Inject(ctx)
+}
+
+// httpErrorHandler renders every error of the gateway as a StandardResponse
+// with the HTTP status of its gRPC code, listing the field violations of
+// the error, and forwards the response header metadata of the RPC
+func httpErrorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	code := 0
+	var httpErr *runtime.HTTPStatusError
+	if errors.As(err, &httpErr) {
+		code, err = httpErr.HTTPStatus, httpErr.Err
+	}
+	st := status.Convert(err)
+	if code == 0 {
+		code = runtime.HTTPStatusFromCode(st.Code())
+	}
+
+	resp := &pb.StandardResponse{
+		Success: false,
+		Code:    uint64(code),
+		Message: st.Message(),
+	}
+	for _, v := range apperr.Violations(err) {
+		resp.Errors = append(resp.Errors, &pb.FieldViolation{Field: v.GetField(), Description: v.GetDescription()})
+	}
+	body, merr := m.Marshal(resp)
+	if merr != nil {
+		slog.ErrorContext(ctx, "Failed to marshal error response", slog.String("error", merr.Error()))
+		runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+		return
+	}
+
+	w.Header().Del("Trailer")
+	w.Header().Del("Transfer-Encoding")
+	if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+		for key, values := range md.HeaderMD {
+			if header, ok := httpOutgoingHeaderMatcher(key); ok {
+				for _, value := range values {
+					w.Header().Add(header, value)
+				}
+			}
+		}
+	}
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	w.Header().Set("Content-Type", m.ContentType(resp))
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// httpOutgoingHeaderMatcher sends the rate limit headers of an RPC as
+// standard HTTP headers, other metadata keeps the Grpc-Metadata- prefix
+func httpOutgoingHeaderMatcher(key string) (string, bool) {
+	if header, ok := ratelimit.Headers[key]; ok {
+		return header, true
+	}
+
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// httpHeaderMatcher forwards the X-Api-Key header to gRPC on top of the
+// headers forwarded by default
+func httpHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, "X-Api-Key") {
+		return auth.APIKeyMetadataKey, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// Middleware recovery for HTTP, answers a panic with a 500 StandardResponse
+func recoveryHTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			recoverPanic(r.Context(), v)
+			if rec.wroteHeader {
+				// Too late to change the response, the client sees a truncated body
+				return
+			}
+
+			writeStandardResponse(w, http.StatusInternalServerError, "Internal Error")
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// Middleware rate limit for HTTP, limits the requests of each client IP
+// before they reach the gateway, answering 429 with a StandardResponse
+func rateLimitHTTPMiddleware(limiter *ratelimit.Limiter, rule ratelimit.Rule, proxies ratelimit.Proxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if rule.Disabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ratelimit.ClientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), proxies)
+			result := limiter.Allow("http|ip:"+ip, rule)
+			if result.Allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			slog.WarnContext(r.Context(), "HTTP rate limit exceeded", slog.String("ip", ip))
+			w.Header().Set(ratelimit.Headers[ratelimit.LimitKey], strconv.Itoa(result.Limit))
+			w.Header().Set(ratelimit.Headers[ratelimit.RemainingKey], strconv.Itoa(result.Remaining))
+			w.Header().Set(ratelimit.Headers[ratelimit.ResetKey], ratelimit.Seconds(result.Reset))
+			w.Header().Set(ratelimit.Headers[ratelimit.RetryAfterKey], ratelimit.Seconds(result.RetryAfter))
+			writeStandardResponse(w, http.StatusTooManyRequests, "Rate limit exceeded, retry in "+ratelimit.Seconds(result.RetryAfter)+"s")
+		})
+	}
+}
+
+// writeStandardResponse answers an HTTP request outside the gateway with a
+// failed StandardResponse
+func writeStandardResponse(w http.ResponseWriter, code int, message string) {
+	body, _ := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(&pb.StandardResponse{
+		Success: false,
+		Code:    uint64(code),
+		Message: message,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
 // Middleware logging for HTTP
 func loggingHTTPMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			startTime := time.Now()
+		startTime := time.Now()
+
+		ctx := logging.With(r.Context(),
+			slog.String("http_method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("peer", r.RemoteAddr),
+		)
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
 
-			next.ServeHTTP(w, r)
+		next.ServeHTTP(rec, r.WithContext(ctx))
 
-			duration := time.Since(startTime)
-			log.Printf("HTTP method=%s path=%s duration=%s", r.Method, r.URL.Path, duration)
+		duration := time.Since(startTime)
+		slog.LogAttrs(ctx, slog.LevelInfo, "HTTP",
+			slog.Int("status", rec.status),
+			slog.Int("bytes", rec.bytes),
+			slog.Duration("duration", duration),
+		)
 	})
-}
\ No newline at end of file
+}
+
+// responseRecorder captures the status code and body size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Flush lets streaming responses through the recorder.
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *pb.StandardResponse
+	}{
+		{
+			name: "field violations",
+			err:  apperr.InvalidArgument("Invalid request", apperr.Field("data.name", "is required")),
+			want: &pb.StandardResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid request",
+				Errors:  []*pb.FieldViolation{{Field: "data.name", Description: "is required"}},
+			},
+		},
+		{
+			name: "not found",
+			err:  apperr.NotFound("Example 1 not found"),
+			want: &pb.StandardResponse{Code: http.StatusNotFound, Message: "Example 1 not found"},
+		},
+		{
+			name: "conflict",
+			err:  apperr.Conflict("name already exists", apperr.Field("name", "already exists")),
+			want: &pb.StandardResponse{
+				Code:    http.StatusConflict,
+				Message: "name already exists",
+				Errors:  []*pb.FieldViolation{{Field: "name", Description: "already exists"}},
+			},
+		},
+		{
+			name: "gateway status",
+			err:  &runtime.HTTPStatusError{HTTPStatus: http.StatusMethodNotAllowed, Err: apperr.InvalidArgument("Method Not Allowed")},
+			want: &pb.StandardResponse{Code: http.StatusMethodNotAllowed, Message: "Method Not Allowed"},
+		},
+	}
+	m := &runtime.JSONPb{}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		httpErrorHandler(context.Background(), runtime.NewServeMux(), m, w, r, tt.err)
+
+		if w.Code != int(tt.want.Code) {
+			t.Errorf("%s: HTTP status %d, want %d", tt.name, w.Code, tt.want.Code)
+		}
+		got := &pb.StandardResponse{}
+		if err := m.Unmarshal(w.Body.Bytes(), got); err != nil {
+			t.Fatalf("%s: unmarshal %s: %v", tt.name, w.Body, err)
+		}
+		if !proto.Equal(got, tt.want) {
+			t.Errorf("%s: response = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
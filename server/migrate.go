@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/config"
+	"github.com/sandisuryadi36/micro-svc-template/server/migration"
+)
+
+const migrateUsage = `Usage: server [config flags] migrate [-dry-run] <command>
+
+Commands:
+  up            apply every pending migration
+  down          roll back the most recently applied migration
+  status        list migrations and whether they are applied
+  to <version>  migrate up or down to the given version (0 rolls back everything)
+`
+
+// runMigrateCommand implements the "migrate" subcommand and returns the exit code.
+func runMigrateCommand(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print the SQL that would be executed without running it")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	initDBMain(cfg.DB)
+	defer closeDBMain()
+
+	migrator, err := migration.New(dbMain)
+	if err != nil {
+		log.Printf("Failed to load migrations: %v", err)
+		return 1
+	}
+	migrator.DryRun = *dryRun
+
+	ctx := context.Background()
+	switch command := flags.Arg(0); command {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	case "to":
+		if flags.NArg() != 2 {
+			flags.Usage()
+			return 2
+		}
+		var version uint64
+		version, err = strconv.ParseUint(flags.Arg(1), 10, 64)
+		if err != nil {
+			log.Printf("Invalid version %q: %v", flags.Arg(1), err)
+			return 2
+		}
+		err = migrator.To(ctx, version)
+	default:
+		log.Printf("Unknown migrate command %q", command)
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		log.Printf("Migration failed: %v", err)
+		return 1
+	}
+
+	return 0
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migration.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,102 @@
+// Package migration applies the versioned SQL schema migrations embedded from
+// the sql directory and records them in the schema_migrations table.
+//
+// Every migration is a pair of files named NNNN_description.up.sql and
+// NNNN_description.down.sql. Versions are applied in ascending order and
+// rolled back in descending order. Each supported database has its own
+// directory under sql, named after the GORM dialector (postgres, sqlite,
+// mysql), and every directory must hold the same versions.
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*/*.sql
+var sqlFiles embed.FS
+
+// Migration is a single reversible schema change.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// String returns the file name prefix of the migration, e.g. 0001_create_example_table.
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Load reads the migrations in the root of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, file := range files {
+		name := path.Base(file)
+		base, direction, ok := cutDirection(name)
+		if !ok {
+			return nil, fmt.Errorf("migration %s: file name must end in .up.sql or .down.sql", name)
+		}
+		prefix, desc, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: file name must start with <version>_", name)
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", name, prefix)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: desc}
+			byVersion[version] = m
+		} else if m.Name != desc {
+			return nil, fmt.Errorf("migration %s: version %d is already used by %s", name, version, m)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %s: both up and down files are required", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func cutDirection(name string) (string, string, bool) {
+	if base, ok := strings.CutSuffix(name, ".up.sql"); ok {
+		return base, "up", true
+	}
+	if base, ok := strings.CutSuffix(name, ".down.sql"); ok {
+		return base, "down", true
+	}
+
+	return "", "", false
+}
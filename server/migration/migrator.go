@@ -0,0 +1,260 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockKey identifies the advisory lock held while migrating, so that only one
+// replica applies migrations at a time.
+const lockKey = 7_262_341_583
+
+// schemaMigration is a row of the schema_migrations table.
+type schemaMigration struct {
+	Version   uint64    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status reports whether a migration has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+
+	// DryRun prints the SQL that would be executed to Out instead of running it.
+	DryRun bool
+	Out    io.Writer
+}
+
+// New returns a Migrator for the migrations embedded in this package that
+// are written for the dialect of db.
+func New(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	dir, err := fs.Sub(sqlFiles, path.Join("sql", dialect))
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migrations for database %q", dialect)
+	}
+
+	return &Migrator{db: db, migrations: migrations, Out: os.Stdout}, nil
+}
+
+// Migrations returns the known migrations, sorted by version.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(tx *gorm.DB) error {
+		applied, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return m.run(tx, m.migrations[i], false)
+			}
+		}
+
+		log.Println("No migration to roll back")
+		return nil
+	})
+}
+
+// To migrates up or down until exactly the migrations up to and including
+// version are applied. Version 0 rolls back every migration.
+func (m *Migrator) To(ctx context.Context, version uint64) error {
+	if version != 0 && !m.exists(version) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func(tx *gorm.DB) error {
+		applied, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; ok && mig.Version > version {
+				if err := m.run(tx, mig, false); err != nil {
+					return err
+				}
+			}
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+				if err := m.run(tx, mig, true); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		appliedAt, ok := applied[mig.Version]
+		result = append(result, Status{Migration: mig, Applied: ok, AppliedAt: appliedAt})
+	}
+
+	return result, nil
+}
+
+func (m *Migrator) exists(version uint64) bool {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applied returns the applied versions and when they were applied.
+func (m *Migrator) applied(tx *gorm.DB) (map[uint64]time.Time, error) {
+	result := map[uint64]time.Time{}
+	if !tx.Migrator().HasTable(&schemaMigration{}) {
+		return result, nil
+	}
+
+	rows := []schemaMigration{}
+	if err := tx.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.Version] = row.AppliedAt
+	}
+
+	return result, nil
+}
+
+// run executes one direction of mig and records it in schema_migrations in
+// the same transaction. MySQL commits implicitly after every DDL statement,
+// so there a failed migration keeps the statements that ran before the
+// failing one and is not recorded. MySQL migrations are a single statement
+// for that reason.
+func (m *Migrator) run(tx *gorm.DB, mig Migration, up bool) error {
+	direction, script := "down", mig.Down
+	if up {
+		direction, script = "up", mig.Up
+	}
+
+	if m.DryRun {
+		fmt.Fprintf(m.Out, "-- %s (%s)\n%s\n", mig, direction, script)
+		return nil
+	}
+
+	log.Printf("Migration %s (%s) begin...", mig, direction)
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(script).Error; err != nil {
+			return err
+		}
+		if up {
+			return tx.Create(&schemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+		}
+		return tx.Delete(&schemaMigration{Version: mig.Version}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %s (%s) failed: %w", mig, direction, err)
+	}
+	log.Printf("Migration %s (%s) finished", mig, direction)
+
+	return nil
+}
+
+// withLock runs fc on a single connection holding the migration lock, after
+// making sure the schema_migrations table exists.
+func (m *Migrator) withLock(ctx context.Context, fc func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(tx *gorm.DB) error {
+		// Start every statement from a clean state while staying on the
+		// connection that holds the lock.
+		tx = tx.Session(&gorm.Session{NewDB: true})
+
+		unlock, err := lock(tx)
+		if err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer unlock()
+
+		if !m.DryRun && !tx.Migrator().HasTable(&schemaMigration{}) {
+			if err := tx.Migrator().CreateTable(&schemaMigration{}); err != nil {
+				return err
+			}
+		}
+
+		return fc(tx)
+	})
+}
+
+// lock takes the migration lock of the dialect of tx and returns the
+// function releasing it. SQLite has no advisory locks, but a database file
+// is rarely shared by replicas and every migration runs in a transaction
+// that takes the write lock anyway. The MySQL lock belongs to the session,
+// so it holds across the commits of DDL statements.
+func lock(tx *gorm.DB) (func(), error) {
+	switch tx.Dialector.Name() {
+	case "postgres":
+		if err := tx.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return nil, err
+		}
+		return func() { tx.Exec("SELECT pg_advisory_unlock(?)", lockKey) }, nil
+	case "mysql":
+		var acquired sql.NullInt64
+		name := fmt.Sprint("schema_migrations_", lockKey)
+		if err := tx.Raw("SELECT GET_LOCK(?, -1)", name).Scan(&acquired).Error; err != nil {
+			return nil, err
+		}
+		if acquired.Int64 != 1 {
+			return nil, fmt.Errorf("lock %s was not granted", name)
+		}
+		return func() { tx.Exec("SELECT RELEASE_LOCK(?)", name) }, nil
+	}
+
+	return func() {}, nil
+}
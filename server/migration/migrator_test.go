@@ -0,0 +1,397 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDriver is SQLite with the advisory lock functions of Postgres and
+// MySQL, which record their calls in locks. GET_LOCK is granted unless
+// locks.err is set.
+const testDriver = "sqlite3_migration_test"
+
+var locks struct {
+	sync.Mutex
+	calls []string
+	// err fails the next lock
+	err error
+}
+
+func init() {
+	sql.Register(testDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			for _, name := range []string{"pg_advisory_lock", "pg_advisory_unlock"} {
+				name := name
+				err := conn.RegisterFunc(name, func(key int64) (int64, error) {
+					return 0, recordLock(fmt.Sprintf("%s(%d)", name, key))
+				}, false)
+				if err != nil {
+					return err
+				}
+			}
+			err := conn.RegisterFunc("GET_LOCK", func(name string, timeout int64) int64 {
+				if recordLock(fmt.Sprintf("GET_LOCK(%s, %d)", name, timeout)) != nil {
+					return 0
+				}
+				return 1
+			}, false)
+			if err != nil {
+				return err
+			}
+			return conn.RegisterFunc("RELEASE_LOCK", func(name string) (int64, error) {
+				return 1, recordLock(fmt.Sprintf("RELEASE_LOCK(%s)", name))
+			}, false)
+		},
+	})
+}
+
+// recordLock records a lock call and returns the error it fails with.
+func recordLock(call string) error {
+	locks.Lock()
+	defer locks.Unlock()
+	locks.calls = append(locks.calls, call)
+	err := locks.err
+	locks.err = nil
+
+	return err
+}
+
+// dialect names a dialector after another database, so the migrator takes
+// the locks of that database.
+type dialect struct {
+	gorm.Dialector
+	name string
+}
+
+func (d dialect) Name() string {
+	return d.name
+}
+
+// lockCalls returns the lock calls made since the last call.
+func lockCalls() []string {
+	locks.Lock()
+	defer locks.Unlock()
+	calls := locks.calls
+	locks.calls = nil
+
+	return calls
+}
+
+// testMigrations keep a log of the down migrations in the steps table, to
+// tell the order they ran in.
+var testMigrations = fstest.MapFS{
+	"0001_create_widgets.up.sql":    {Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY); CREATE TABLE steps (name TEXT);")},
+	"0001_create_widgets.down.sql":  {Data: []byte("DROP TABLE steps; DROP TABLE widgets;")},
+	"0002_add_widget_name.up.sql":   {Data: []byte("ALTER TABLE widgets ADD COLUMN name TEXT;")},
+	"0002_add_widget_name.down.sql": {Data: []byte("INSERT INTO steps VALUES ('down 2'); ALTER TABLE widgets DROP COLUMN name;")},
+	"0003_create_parts.up.sql":      {Data: []byte("CREATE TABLE parts (id INTEGER PRIMARY KEY);")},
+	"0003_create_parts.down.sql":    {Data: []byte("INSERT INTO steps VALUES ('down 3'); DROP TABLE parts;")},
+	"0004_insert_missing.up.sql":    {Data: []byte("INSERT INTO parts (id) VALUES (1); INSERT INTO missing VALUES (1);")},
+	"0004_insert_missing.down.sql":  {Data: []byte("DELETE FROM parts;")},
+}
+
+// openSQLite returns an in-memory SQLite database, kept on a single
+// connection as it only lives as long as its connection.
+func openSQLite(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	t.Helper()
+	gdb, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return gdb
+}
+
+// newTestMigrator returns a Migrator of the first n test migrations on
+// SQLite, taking the locks of the database named dialect.
+func newTestMigrator(t *testing.T, n int, name string) (*Migrator, *gorm.DB) {
+	t.Helper()
+	gdb := openSQLite(t, dialect{Dialector: sqlite.Dialector{DriverName: testDriver, DSN: ":memory:"}, name: name})
+	migrations, err := Load(testMigrations)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	lockCalls()
+
+	return &Migrator{db: gdb, migrations: migrations[:n]}, gdb
+}
+
+// appliedVersions returns the versions recorded in schema_migrations.
+func appliedVersions(t *testing.T, m *Migrator) []uint64 {
+	t.Helper()
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	var versions []uint64
+	for _, s := range statuses {
+		if s.Applied {
+			versions = append(versions, s.Version)
+		}
+	}
+
+	return versions
+}
+
+func TestTo(t *testing.T) {
+	ctx := context.Background()
+	m, gdb := newTestMigrator(t, 4, "sqlite")
+
+	if err := m.To(ctx, 3); err != nil {
+		t.Fatalf("To(3): %v", err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, []uint64{1, 2, 3}) {
+		t.Errorf("applied after To(3) = %v, want 1, 2 and 3", got)
+	}
+
+	// Down to 1 rolls back 3 then 2
+	if err := m.To(ctx, 1); err != nil {
+		t.Fatalf("To(1): %v", err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("applied after To(1) = %v, want 1", got)
+	}
+	var steps []string
+	if err := gdb.Table("steps").Order("rowid").Pluck("name", &steps).Error; err != nil {
+		t.Fatalf("steps: %v", err)
+	}
+	if !reflect.DeepEqual(steps, []string{"down 3", "down 2"}) {
+		t.Errorf("down migrations ran as %v, want down 3 then down 2", steps)
+	}
+	if gdb.Migrator().HasTable("parts") || gdb.Migrator().HasColumn("widgets", "name") {
+		t.Errorf("parts or widgets.name still exists after To(1)")
+	}
+
+	if err := m.To(ctx, 1); err != nil {
+		t.Fatalf("To(1) again: %v", err)
+	}
+	if err := m.To(ctx, 9); err == nil || err.Error() != "unknown migration version 9" {
+		t.Errorf("To(9) = %v, want an unknown version error", err)
+	}
+
+	// A failing migration is rolled back and not recorded, the ones before
+	// it stay applied
+	err := m.To(ctx, 4)
+	if err == nil || !strings.Contains(err.Error(), "migration 0004_insert_missing (up) failed") {
+		t.Errorf("To(4) = %v, want the failure of 0004", err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, []uint64{1, 2, 3}) {
+		t.Errorf("applied after a failed To(4) = %v, want 1, 2 and 3", got)
+	}
+	var parts int64
+	if err := gdb.Table("parts").Count(&parts).Error; err != nil || parts != 0 {
+		t.Errorf("parts holds %d rows (%v) after the failed migration, want none", parts, err)
+	}
+
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To(0): %v", err)
+	}
+	if got := appliedVersions(t, m); got != nil {
+		t.Errorf("applied after To(0) = %v, want none", got)
+	}
+	if gdb.Migrator().HasTable("widgets") {
+		t.Errorf("widgets still exists after To(0)")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	m, gdb := newTestMigrator(t, 3, "sqlite")
+	var out bytes.Buffer
+	m.DryRun, m.Out = true, &out
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	want := "-- 0001_create_widgets (up)\n" + string(testMigrations["0001_create_widgets.up.sql"].Data) + "\n" +
+		"-- 0002_add_widget_name (up)\n" + string(testMigrations["0002_add_widget_name.up.sql"].Data) + "\n" +
+		"-- 0003_create_parts (up)\n" + string(testMigrations["0003_create_parts.up.sql"].Data) + "\n"
+	if out.String() != want {
+		t.Errorf("Up printed:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if gdb.Migrator().HasTable("widgets") || gdb.Migrator().HasTable(&schemaMigration{}) {
+		t.Errorf("a dry run changed the database")
+	}
+
+	m.DryRun = false
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	m.DryRun = true
+	out.Reset()
+	if err := m.To(ctx, 1); err != nil {
+		t.Fatalf("To(1): %v", err)
+	}
+	want = "-- 0003_create_parts (down)\n" + string(testMigrations["0003_create_parts.down.sql"].Data) + "\n" +
+		"-- 0002_add_widget_name (down)\n" + string(testMigrations["0002_add_widget_name.down.sql"].Data) + "\n"
+	if out.String() != want {
+		t.Errorf("To(1) printed:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, []uint64{1, 2, 3}) {
+		t.Errorf("applied after a dry run To(1) = %v, want 1, 2 and 3", got)
+	}
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	ctx := context.Background()
+	gdb := openSQLite(t, sqlite.Open(":memory:"))
+	m, err := New(gdb)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	versions := func(n int) []uint64 {
+		var versions []uint64
+		for _, mig := range m.Migrations()[:n] {
+			versions = append(versions, mig.Version)
+		}
+		return versions
+	}
+	all := len(m.Migrations())
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, versions(all)) {
+		t.Errorf("applied after Up = %v, want %v", got, versions(all))
+	}
+	// Nothing is pending anymore
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("second Up: %v", err)
+	}
+
+	if err := m.Down(ctx); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, versions(all-1)) {
+		t.Errorf("applied after Down = %v, want %v", got, versions(all-1))
+	}
+	if gdb.Migrator().HasTable("api_keys") {
+		t.Errorf("api_keys still exists after Down")
+	}
+
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To(0): %v", err)
+	}
+	if got := appliedVersions(t, m); got != nil {
+		t.Errorf("applied after To(0) = %v, want none", got)
+	}
+	if gdb.Migrator().HasTable("example_table") {
+		t.Errorf("example_table still exists after To(0)")
+	}
+
+	// The down migrations leave a schema the up ones apply to again
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up after To(0): %v", err)
+	}
+	if got := appliedVersions(t, m); !reflect.DeepEqual(got, versions(all)) {
+		t.Errorf("applied after Up = %v, want %v", got, versions(all))
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	mysqlLock := fmt.Sprint("schema_migrations_", lockKey)
+	tests := []struct {
+		dialect      string
+		lock, unlock []string
+		lockErr      string
+	}{
+		{
+			dialect: "postgres",
+			lock:    []string{fmt.Sprintf("pg_advisory_lock(%d)", lockKey)},
+			unlock:  []string{fmt.Sprintf("pg_advisory_unlock(%d)", lockKey)},
+			lockErr: "acquire migration lock: lock timeout",
+		},
+		{
+			dialect: "mysql",
+			lock:    []string{fmt.Sprintf("GET_LOCK(%s, -1)", mysqlLock)},
+			unlock:  []string{fmt.Sprintf("RELEASE_LOCK(%s)", mysqlLock)},
+			lockErr: fmt.Sprintf("acquire migration lock: lock %s was not granted", mysqlLock),
+		},
+		// SQLite relies on the write lock of the migration transactions
+		{dialect: "sqlite"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			testWithLock(t, tt.dialect, tt.lock, tt.unlock, tt.lockErr)
+		})
+	}
+}
+
+func testWithLock(t *testing.T, name string, lock, unlock []string, lockErr string) {
+	ctx := context.Background()
+	m, gdb := newTestMigrator(t, 1, name)
+	both := append(append([]string(nil), lock...), unlock...)
+
+	err := m.withLock(ctx, func(tx *gorm.DB) error {
+		if !tx.Migrator().HasTable(&schemaMigration{}) {
+			t.Errorf("schema_migrations missing in withLock")
+		}
+		if got := lockCalls(); !reflect.DeepEqual(got, lock) {
+			t.Errorf("locks taken before fc = %v, want %v", got, lock)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withLock: %v", err)
+	}
+	if got := lockCalls(); !reflect.DeepEqual(got, unlock) {
+		t.Errorf("locks after fc = %v, want %v", got, unlock)
+	}
+
+	// The lock is released when fc fails
+	errFail := errors.New("fail")
+	if err := m.withLock(ctx, func(tx *gorm.DB) error { return errFail }); !errors.Is(err, errFail) {
+		t.Errorf("withLock = %v, want %v", err, errFail)
+	}
+	if got := lockCalls(); !reflect.DeepEqual(got, both) {
+		t.Errorf("locks = %v, want %v", got, both)
+	}
+
+	// Nothing runs without the lock
+	if lockErr != "" {
+		locks.Lock()
+		locks.err = errors.New("lock timeout")
+		locks.Unlock()
+		err = m.withLock(ctx, func(tx *gorm.DB) error {
+			t.Errorf("fc called without the lock")
+			return nil
+		})
+		if err == nil || err.Error() != lockErr {
+			t.Errorf("withLock = %v, want %s", err, lockErr)
+		}
+		if got := lockCalls(); !reflect.DeepEqual(got, lock) {
+			t.Errorf("locks = %v, want only %v", got, lock)
+		}
+	}
+
+	if err := gdb.Migrator().DropTable(&schemaMigration{}); err != nil {
+		t.Fatal(err)
+	}
+	m.DryRun = true
+	if err := m.withLock(ctx, func(tx *gorm.DB) error { return nil }); err != nil {
+		t.Fatalf("withLock: %v", err)
+	}
+	if gdb.Migrator().HasTable(&schemaMigration{}) {
+		t.Errorf("withLock created schema_migrations in a dry run")
+	}
+	lockCalls()
+}
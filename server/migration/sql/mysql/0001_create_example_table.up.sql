@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS example_table (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    crated_at DATETIME(6),
+    updated_at DATETIME(6),
+    deleted_at DATETIME(6)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE example_table
+    DROP COLUMN description,
+    DROP COLUMN name;
@@ -0,0 +1,3 @@
+ALTER TABLE example_table
+    ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN description TEXT NOT NULL DEFAULT ('');
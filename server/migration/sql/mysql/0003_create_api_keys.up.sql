@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    prefix VARCHAR(64) NOT NULL DEFAULT '',
+    key_hash VARCHAR(255) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL DEFAULT (''),
+    created_at DATETIME(6),
+    expires_at DATETIME(6),
+    last_used_at DATETIME(6),
+    revoked_at DATETIME(6)
+);
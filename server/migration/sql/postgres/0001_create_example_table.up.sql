@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS example_table (
+    id BIGSERIAL PRIMARY KEY,
+    crated_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
@@ -0,0 +1,2 @@
+ALTER TABLE example_table DROP COLUMN IF EXISTS description;
+ALTER TABLE example_table DROP COLUMN IF EXISTS name;
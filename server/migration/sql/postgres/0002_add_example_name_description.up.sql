@@ -0,0 +1,2 @@
+ALTER TABLE example_table ADD COLUMN IF NOT EXISTS name TEXT NOT NULL DEFAULT '';
+ALTER TABLE example_table ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS example_table (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    crated_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
@@ -0,0 +1,2 @@
+ALTER TABLE example_table DROP COLUMN description;
+ALTER TABLE example_table DROP COLUMN name;
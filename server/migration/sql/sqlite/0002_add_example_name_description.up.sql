@@ -0,0 +1,2 @@
+ALTER TABLE example_table ADD COLUMN name TEXT NOT NULL DEFAULT '';
+ALTER TABLE example_table ADD COLUMN description TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,155 @@
+// Package ormutil implements, on top of gorm.io/gorm (v2), the helpers that
+// the code generated by protoc-gen-gorm calls through its gorm1 import.
+// cmd/protoc-gen-gormv2 points the generated files at this package instead
+// of atlas-app-toolkit's jinzhu/gorm based implementation.
+package ormutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/infobloxopen/atlas-app-toolkit/query"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ApplyCollectionOperators applies filtering, sorting, pagination and field
+// selection to db. Nil operators are skipped.
+func ApplyCollectionOperators(ctx context.Context, db *gorm.DB, obj interface{}, pb proto.Message, f *query.Filtering, s *query.Sorting, p *query.Pagination, fs *query.FieldSelection) (*gorm.DB, error) {
+	db, err := ApplyFiltering(ctx, db, f, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err = ApplySorting(ctx, db, s, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	db = ApplyPagination(ctx, db, p)
+
+	return ApplyFieldSelection(ctx, db, fs, obj)
+}
+
+// ApplyFiltering applies filtering operator f to gorm instance db.
+func ApplyFiltering(ctx context.Context, db *gorm.DB, f *query.Filtering, obj interface{}) (*gorm.DB, error) {
+	if f.GetRoot() == nil {
+		return db, nil
+	}
+
+	sch, err := parseSchema(db, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &filterConverter{db: db, schema: sch}
+	where, args, err := c.node(f.GetOperator(), f.GetStringCondition(), f.GetNumberCondition(),
+		f.GetNullCondition(), f.GetStringArrayCondition(), f.GetNumberArrayCondition())
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Where(where, args...), nil
+}
+
+// ApplySorting applies sorting operator s to gorm instance db.
+func ApplySorting(ctx context.Context, db *gorm.DB, s *query.Sorting, obj interface{}) (*gorm.DB, error) {
+	if len(s.GetCriterias()) == 0 {
+		return db, nil
+	}
+
+	sch, err := parseSchema(db, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range s.GetCriterias() {
+		column, err := ColumnName(sch, cr.GetTag())
+		if err != nil {
+			return nil, err
+		}
+		order := db.Statement.Quote(column)
+		if cr.GetOrder() == query.SortCriteria_DESC {
+			order += " DESC"
+		}
+		db = db.Order(order)
+	}
+
+	return db, nil
+}
+
+// ApplyPagination applies pagination operator p to gorm instance db.
+func ApplyPagination(ctx context.Context, db *gorm.DB, p *query.Pagination) *gorm.DB {
+	if offset := p.GetOffset(); offset > 0 {
+		db = db.Offset(int(offset))
+	}
+	if limit := p.GetLimit(); limit > 0 {
+		db = db.Limit(int(limit))
+	}
+
+	return db
+}
+
+// ApplyFieldSelection preloads the associations of obj named in fs.
+// Plain columns are always loaded, as with the jinzhu/gorm implementation.
+func ApplyFieldSelection(ctx context.Context, db *gorm.DB, fs *query.FieldSelection, obj interface{}) (*gorm.DB, error) {
+	if len(fs.GetFields()) == 0 {
+		return db, nil
+	}
+
+	sch, err := parseSchema(db, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range fs.GetFields() {
+		field := lookUpField(sch, name)
+		if field == nil {
+			return nil, fmt.Errorf("Field %q doesn't exist in %s", name, sch.Name)
+		}
+		if _, ok := sch.Relationships.Relations[field.Name]; ok {
+			db = db.Preload(field.Name)
+		}
+	}
+
+	return db, nil
+}
+
+// ColumnName resolves a proto field name, its JSON name or the Go field
+// name of an ORM type to the database column it is stored in.
+func ColumnName(sch *schema.Schema, name string) (string, error) {
+	if strings.Contains(name, ".") {
+		return "", fmt.Errorf("Nested field path %q is not supported", name)
+	}
+	field := lookUpField(sch, name)
+	if field == nil || field.DBName == "" {
+		return "", fmt.Errorf("Field %q doesn't exist in %s", name, sch.Name)
+	}
+
+	return field.DBName, nil
+}
+
+func lookUpField(sch *schema.Schema, name string) *schema.Field {
+	if name == "" {
+		return nil
+	}
+	if field := sch.LookUpField(name); field != nil {
+		return field
+	}
+	if field := sch.LookUpField(strings.ToUpper(name[:1]) + name[1:]); field != nil {
+		return field
+	}
+
+	return sch.LookUpField(schema.NamingStrategy{}.ColumnName("", name))
+}
+
+func parseSchema(db *gorm.DB, obj interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(obj); err != nil {
+		return nil, err
+	}
+
+	return stmt.Schema, nil
+}
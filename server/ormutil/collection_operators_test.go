@@ -0,0 +1,155 @@
+package ormutil
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/infobloxopen/atlas-app-toolkit/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+type widgetORM struct {
+	Id        uint64
+	Name      string
+	Size      int64
+	CreatedAt *time.Time
+	Parts     []partORM `gorm:"foreignKey:WidgetId"`
+}
+
+type partORM struct {
+	Id       uint64
+	WidgetId uint64
+}
+
+// dryRun returns a database that builds the statements without running
+// them.
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	return db
+}
+
+func TestApplyCollectionOperators(t *testing.T) {
+	mustFilter := func(text string) *query.Filtering {
+		f, err := query.ParseFiltering(text)
+		if err != nil {
+			t.Fatalf("ParseFiltering(%q): %v", text, err)
+		}
+		return f
+	}
+	mustSort := func(text string) *query.Sorting {
+		s, err := query.ParseSorting(text)
+		if err != nil {
+			t.Fatalf("ParseSorting(%q): %v", text, err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		f       *query.Filtering
+		s       *query.Sorting
+		p       *query.Pagination
+		fs      *query.FieldSelection
+		sql     string
+		vars    []interface{}
+		wantErr bool
+	}{
+		{name: "no operators", sql: "SELECT * FROM `widget_orms`"},
+		{
+			name: "string and number conditions",
+			f:    mustFilter(`name == "a" and size > 2`),
+			sql:  "SELECT * FROM `widget_orms` WHERE (`name` = ? AND `size` > ?)",
+			vars: []interface{}{"a", float64(2)},
+		},
+		{
+			name: "negated or",
+			f:    mustFilter(`not (name ~ "^a" or size <= 1)`),
+			sql:  "SELECT * FROM `widget_orms` WHERE NOT ((`name` ~ ? OR `size` <= ?))",
+			vars: []interface{}{"^a", float64(1)},
+		},
+		{
+			name: "null and in conditions by JSON and Go names",
+			f:    mustFilter(`createdAt == null and Size in [1, 2] and name != "b"`),
+			sql:  "SELECT * FROM `widget_orms` WHERE ((`created_at` IS NULL AND `size` IN (?,?)) AND NOT (`name` = ?))",
+			vars: []interface{}{float64(1), float64(2), "b"},
+		},
+		{
+			name: "case insensitive equality",
+			f:    mustFilter(`name ieq "A"`),
+			sql:  "SELECT * FROM `widget_orms` WHERE lower(`name`) = lower(?)",
+			vars: []interface{}{"A"},
+		},
+		{
+			name: "sorting and pagination",
+			s:    mustSort("size desc, created_at"),
+			p:    &query.Pagination{Offset: 20, Limit: 10},
+			sql:  "SELECT * FROM `widget_orms` ORDER BY `size` DESC,`created_at` LIMIT 10 OFFSET 20",
+		},
+		{name: "field selection of a column", fs: query.ParseFieldSelection("name"), sql: "SELECT * FROM `widget_orms`"},
+		{name: "unknown filter field", f: mustFilter(`color == "red"`), wantErr: true},
+		{name: "nested filter field", f: mustFilter(`parts.id == 1`), wantErr: true},
+		{name: "unknown sort field", s: mustSort("color"), wantErr: true},
+		{name: "unknown selected field", fs: query.ParseFieldSelection("color"), wantErr: true},
+	}
+	for _, tt := range tests {
+		db, err := ApplyCollectionOperators(context.Background(), dryRun(t), &widgetORM{}, nil, tt.f, tt.s, tt.p, tt.fs)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: ApplyCollectionOperators succeeded, want an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ApplyCollectionOperators: %v", tt.name, err)
+			continue
+		}
+		stmt := db.Find(&[]widgetORM{}).Statement
+		if got := stmt.SQL.String(); got != tt.sql {
+			t.Errorf("%s: SQL = %s, want %s", tt.name, got, tt.sql)
+		}
+		if got := flatten(stmt.Vars); !reflect.DeepEqual(got, tt.vars) {
+			t.Errorf("%s: vars = %v, want %v", tt.name, got, tt.vars)
+		}
+	}
+}
+
+func TestApplyFieldSelectionPreloads(t *testing.T) {
+	db, err := ApplyFieldSelection(context.Background(), dryRun(t), query.ParseFieldSelection("parts,name"), &widgetORM{})
+	if err != nil {
+		t.Fatalf("ApplyFieldSelection: %v", err)
+	}
+	preloads := make([]string, 0, len(db.Statement.Preloads))
+	for name := range db.Statement.Preloads {
+		preloads = append(preloads, name)
+	}
+	if strings.Join(preloads, ",") != "Parts" {
+		t.Errorf("preloads = %v, want only Parts", preloads)
+	}
+}
+
+// flatten expands the slices bound to IN conditions.
+func flatten(vars []interface{}) []interface{} {
+	var out []interface{}
+	for _, v := range vars {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			out = append(out, v)
+			continue
+		}
+		for i := 0; i < rv.Len(); i++ {
+			out = append(out, rv.Index(i).Interface())
+		}
+	}
+
+	return out
+}
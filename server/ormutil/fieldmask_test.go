@@ -0,0 +1,99 @@
+package ormutil
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+type inner struct {
+	Value string
+	Count int
+}
+
+type outer struct {
+	Name  string
+	Inner *inner
+	Tags  []string
+	Plain inner
+}
+
+func TestMergeWithMask(t *testing.T) {
+	source := func() *outer {
+		return &outer{
+			Name:  "new",
+			Inner: &inner{Value: "new value", Count: 2},
+			Tags:  []string{"a"},
+			Plain: inner{Value: "new plain"},
+		}
+	}
+	dest := func() *outer {
+		return &outer{
+			Name:  "old",
+			Inner: &inner{Value: "old value", Count: 1},
+			Plain: inner{Value: "old plain", Count: 1},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		source  interface{}
+		dest    interface{}
+		paths   []string
+		want    *outer
+		wantErr bool
+	}{
+		{name: "no mask", source: source(), dest: dest(), want: dest()},
+		{
+			name:  "top level fields",
+			paths: []string{"Name", "Tags"}, source: source(), dest: dest(),
+			want: &outer{Name: "new", Inner: &inner{Value: "old value", Count: 1}, Tags: []string{"a"}, Plain: inner{Value: "old plain", Count: 1}},
+		},
+		{
+			name:  "nested field through a pointer",
+			paths: []string{"Inner.Value"}, source: source(), dest: dest(),
+			want: &outer{Name: "old", Inner: &inner{Value: "new value", Count: 1}, Plain: inner{Value: "old plain", Count: 1}},
+		},
+		{
+			name:  "nested field of a value",
+			paths: []string{"Plain.Value"}, source: source(), dest: dest(),
+			want: &outer{Name: "old", Inner: &inner{Value: "old value", Count: 1}, Plain: inner{Value: "new plain", Count: 1}},
+		},
+		{
+			name:  "nil destination pointer is allocated",
+			paths: []string{"Inner.Count"}, source: source(), dest: &outer{},
+			want: &outer{Inner: &inner{Count: 2}},
+		},
+		{
+			name:  "whole struct",
+			paths: []string{"Inner"}, source: source(), dest: dest(),
+			want: &outer{Name: "old", Inner: &inner{Value: "new value", Count: 2}, Plain: inner{Value: "old plain", Count: 1}},
+		},
+		{name: "unknown field", paths: []string{"Missing"}, source: source(), dest: dest(), wantErr: true},
+		{name: "unknown nested field", paths: []string{"Inner.Missing"}, source: source(), dest: dest(), wantErr: true},
+		{name: "nil source", paths: []string{"Name"}, source: nil, dest: dest(), wantErr: true},
+		{name: "nil destination", paths: []string{"Name"}, source: source(), dest: nil, wantErr: true},
+		{name: "other types", paths: []string{"Value"}, source: &inner{}, dest: dest(), wantErr: true},
+	}
+	for _, tt := range tests {
+		var mask *fieldmaskpb.FieldMask
+		if tt.paths != nil {
+			mask = &fieldmaskpb.FieldMask{Paths: tt.paths}
+		}
+		err := MergeWithMask(tt.source, tt.dest, mask)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: MergeWithMask succeeded, want an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: MergeWithMask: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.dest, tt.want) {
+			t.Errorf("%s: MergeWithMask = %+v, want %+v", tt.name, tt.dest, tt.want)
+		}
+	}
+}
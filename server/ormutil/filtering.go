@@ -0,0 +1,201 @@
+package ormutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/infobloxopen/atlas-app-toolkit/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// filterConverter renders an atlas filtering tree as a SQL condition for the
+// columns of schema.
+type filterConverter struct {
+	db     *gorm.DB
+	schema *schema.Schema
+}
+
+// node renders whichever of the oneof members of a filtering node is set.
+func (c *filterConverter) node(op *query.LogicalOperator, s *query.StringCondition, n *query.NumberCondition,
+	null *query.NullCondition, sa *query.StringArrayCondition, na *query.NumberArrayCondition) (string, []interface{}, error) {
+	switch {
+	case op != nil:
+		return c.operator(op)
+	case s != nil:
+		return c.stringCondition(s)
+	case n != nil:
+		return c.numberCondition(n)
+	case null != nil:
+		return c.nullCondition(null)
+	case sa != nil:
+		return c.inCondition(sa.GetFieldPath(), sa.GetValues(), sa.GetIsNegative())
+	case na != nil:
+		return c.inCondition(na.GetFieldPath(), na.GetValues(), na.GetIsNegative())
+	}
+
+	return "", nil, fmt.Errorf("Empty filtering node")
+}
+
+func (c *filterConverter) operator(op *query.LogicalOperator) (string, []interface{}, error) {
+	left, leftArgs, err := c.node(op.GetLeftOperator(), op.GetLeftStringCondition(), op.GetLeftNumberCondition(),
+		op.GetLeftNullCondition(), op.GetLeftStringArrayCondition(), op.GetLeftNumberArrayCondition())
+	if err != nil {
+		return "", nil, err
+	}
+	right, rightArgs, err := c.node(op.GetRightOperator(), op.GetRightStringCondition(), op.GetRightNumberCondition(),
+		op.GetRightNullCondition(), op.GetRightStringArrayCondition(), op.GetRightNumberArrayCondition())
+	if err != nil {
+		return "", nil, err
+	}
+
+	join := "AND"
+	if op.GetType() == query.LogicalOperator_OR {
+		join = "OR"
+	}
+
+	return negate(fmt.Sprintf("(%s %s %s)", left, join, right), op.GetIsNegative()), append(leftArgs, rightArgs...), nil
+}
+
+func (c *filterConverter) stringCondition(s *query.StringCondition) (string, []interface{}, error) {
+	column, err := c.column(s.GetFieldPath())
+	if err != nil {
+		return "", nil, err
+	}
+
+	var where string
+	switch s.GetType() {
+	case query.StringCondition_EQ:
+		where = column + " = ?"
+	case query.StringCondition_MATCH:
+		where = column + " " + c.matchOperator() + " ?"
+	case query.StringCondition_GT:
+		where = column + " > ?"
+	case query.StringCondition_GE:
+		where = column + " >= ?"
+	case query.StringCondition_LT:
+		where = column + " < ?"
+	case query.StringCondition_LE:
+		where = column + " <= ?"
+	case query.StringCondition_IEQ:
+		where = "lower(" + column + ") = lower(?)"
+	default:
+		return "", nil, fmt.Errorf("Unknown string condition %s", s.GetType())
+	}
+
+	return negate(where, s.GetIsNegative()), []interface{}{s.GetValue()}, nil
+}
+
+// matchOperator returns the regular expression match operator of the
+// dialect. SQLite only has REGEXP when the driver registers the function.
+func (c *filterConverter) matchOperator() string {
+	switch c.db.Dialector.Name() {
+	case "sqlite", "mysql":
+		return "REGEXP"
+	}
+
+	return "~"
+}
+
+func (c *filterConverter) numberCondition(n *query.NumberCondition) (string, []interface{}, error) {
+	column, err := c.column(n.GetFieldPath())
+	if err != nil {
+		return "", nil, err
+	}
+
+	var where string
+	switch n.GetType() {
+	case query.NumberCondition_EQ:
+		where = column + " = ?"
+	case query.NumberCondition_GT:
+		where = column + " > ?"
+	case query.NumberCondition_GE:
+		where = column + " >= ?"
+	case query.NumberCondition_LT:
+		where = column + " < ?"
+	case query.NumberCondition_LE:
+		where = column + " <= ?"
+	default:
+		return "", nil, fmt.Errorf("Unknown number condition %s", n.GetType())
+	}
+
+	return negate(where, n.GetIsNegative()), []interface{}{n.GetValue()}, nil
+}
+
+func (c *filterConverter) nullCondition(n *query.NullCondition) (string, []interface{}, error) {
+	column, err := c.column(n.GetFieldPath())
+	if err != nil {
+		return "", nil, err
+	}
+
+	return negate(column+" IS NULL", n.GetIsNegative()), nil, nil
+}
+
+func (c *filterConverter) inCondition(fieldPath []string, values interface{}, isNegative bool) (string, []interface{}, error) {
+	column, err := c.column(fieldPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return negate(column+" IN (?)", isNegative), []interface{}{values}, nil
+}
+
+func (c *filterConverter) column(fieldPath []string) (string, error) {
+	if len(fieldPath) != 1 {
+		return "", fmt.Errorf("Field path %v is not supported", fieldPath)
+	}
+	column, err := ColumnName(c.schema, fieldPath[0])
+	if err != nil {
+		return "", err
+	}
+
+	return c.db.Statement.Quote(column), nil
+}
+
+func negate(where string, isNegative bool) string {
+	if isNegative {
+		return "NOT (" + where + ")"
+	}
+
+	return where
+}
+
+// FieldPaths returns the field paths compared by filtering f, joined with
+// dots, so callers can check them before applying f.
+func FieldPaths(f *query.Filtering) []string {
+	var paths []string
+	RewriteFieldPaths(f, func(path []string) []string {
+		paths = append(paths, strings.Join(path, "."))
+		return path
+	})
+
+	return paths
+}
+
+// RewriteFieldPaths replaces the field path of every condition of f by the
+// result of rewrite.
+func RewriteFieldPaths(f *query.Filtering, rewrite func(path []string) []string) {
+	rewriteNode(rewrite, f.GetOperator(), f.GetStringCondition(), f.GetNumberCondition(),
+		f.GetNullCondition(), f.GetStringArrayCondition(), f.GetNumberArrayCondition())
+}
+
+func rewriteNode(rewrite func(path []string) []string, op *query.LogicalOperator, s *query.StringCondition, n *query.NumberCondition,
+	null *query.NullCondition, sa *query.StringArrayCondition, na *query.NumberArrayCondition) {
+	switch {
+	case op != nil:
+		rewriteNode(rewrite, op.GetLeftOperator(), op.GetLeftStringCondition(), op.GetLeftNumberCondition(),
+			op.GetLeftNullCondition(), op.GetLeftStringArrayCondition(), op.GetLeftNumberArrayCondition())
+		rewriteNode(rewrite, op.GetRightOperator(), op.GetRightStringCondition(), op.GetRightNumberCondition(),
+			op.GetRightNullCondition(), op.GetRightStringArrayCondition(), op.GetRightNumberArrayCondition())
+	case s != nil:
+		s.FieldPath = rewrite(s.FieldPath)
+	case n != nil:
+		n.FieldPath = rewrite(n.FieldPath)
+	case null != nil:
+		null.FieldPath = rewrite(null.FieldPath)
+	case sa != nil:
+		sa.FieldPath = rewrite(sa.FieldPath)
+	case na != nil:
+		na.FieldPath = rewrite(na.FieldPath)
+	}
+}
@@ -0,0 +1,71 @@
+package ormutil
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/infobloxopen/atlas-app-toolkit/query"
+)
+
+func TestRewriteFieldPaths(t *testing.T) {
+	// fields maps the paths of the API to the Go fields of the model
+	fields := map[string]string{
+		"name":       "Name",
+		"size":       "Size",
+		"created_at": "CreatedAt",
+		"parts.id":   "Parts.Id",
+	}
+	rewrite := func(path []string) []string {
+		return strings.Split(fields[strings.Join(path, ".")], ".")
+	}
+
+	tests := []struct {
+		filter    string
+		paths     []string
+		rewritten []string
+		sql       string
+	}{
+		{filter: `name == "a"`, paths: []string{"name"}, rewritten: []string{"Name"}, sql: "`name` = ?"},
+		{
+			filter:    `created_at == null or not (size > 1 and size in [1, 2])`,
+			paths:     []string{"created_at", "size", "size"},
+			rewritten: []string{"CreatedAt", "Size", "Size"},
+			sql:       "(`created_at` IS NULL OR NOT ((`size` > ? AND `size` IN (?,?))))",
+		},
+		{
+			filter:    `name in ["a", "b"] and parts.id == 1`,
+			paths:     []string{"name", "parts.id"},
+			rewritten: []string{"Name", "Parts.Id"},
+		},
+	}
+	for _, tt := range tests {
+		f, err := query.ParseFiltering(tt.filter)
+		if err != nil {
+			t.Fatalf("ParseFiltering(%q): %v", tt.filter, err)
+		}
+		if got := FieldPaths(f); !reflect.DeepEqual(got, tt.paths) {
+			t.Errorf("FieldPaths(%q) = %v, want %v", tt.filter, got, tt.paths)
+		}
+		RewriteFieldPaths(f, rewrite)
+		if got := FieldPaths(f); !reflect.DeepEqual(got, tt.rewritten) {
+			t.Errorf("FieldPaths(%q) after RewriteFieldPaths = %v, want %v", tt.filter, got, tt.rewritten)
+		}
+
+		db, err := ApplyFiltering(context.Background(), dryRun(t), f, &widgetORM{})
+		if tt.sql == "" {
+			if err == nil {
+				t.Errorf("ApplyFiltering(%q) succeeded, want an error for the nested path", tt.filter)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ApplyFiltering(%q): %v", tt.filter, err)
+			continue
+		}
+		if got := db.Find(&[]widgetORM{}).Statement.SQL.String(); got != "SELECT * FROM `widget_orms` WHERE "+tt.sql {
+			t.Errorf("ApplyFiltering(%q) SQL = %s, want the condition %s", tt.filter, got, tt.sql)
+		}
+	}
+}
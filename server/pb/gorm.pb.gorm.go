@@ -3,20 +3,23 @@ package pb
 import (
 	context "context"
 	fmt "fmt"
-	gorm1 "github.com/infobloxopen/atlas-app-toolkit/gorm"
 	errors "github.com/infobloxopen/protoc-gen-gorm/errors"
-	gorm "github.com/jinzhu/gorm"
+	gorm1 "github.com/sandisuryadi36/micro-svc-template/server/ormutil"
 	field_mask "google.golang.org/genproto/protobuf/field_mask"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	gorm "gorm.io/gorm"
+	clause "gorm.io/gorm/clause"
 	strings "strings"
 	time "time"
 )
 
 type ExampleORM struct {
-	CratedAt  *time.Time
-	DeletedAt *time.Time
-	Id        uint64 `gorm:"primary_key;not null"`
-	UpdatedAt *time.Time
+	CratedAt    *time.Time
+	DeletedAt   *time.Time
+	Description string
+	Id          uint64 `gorm:"primary_key;not null"`
+	Name        string
+	UpdatedAt   *time.Time
 }
 
 // TableName overrides the default tablename generated by GORM
@@ -47,6 +50,8 @@ func (m *Example) ToORM(ctx context.Context) (ExampleORM, error) {
 		t := m.DeletedAt.AsTime()
 		to.DeletedAt = &t
 	}
+	to.Name = m.Name
+	to.Description = m.Description
 	if posthook, ok := interface{}(m).(ExampleWithAfterToORM); ok {
 		err = posthook.AfterToORM(ctx, &to)
 	}
@@ -55,11 +60,11 @@ func (m *Example) ToORM(ctx context.Context) (ExampleORM, error) {
 
 // ToPB runs the BeforeToPB hook if present, converts the fields of this
 // object to PB format, runs the AfterToPB hook, then returns the PB object
-func (m *ExampleORM) ToPB(ctx context.Context) (Example, error) {
-	to := Example{}
+func (m *ExampleORM) ToPB(ctx context.Context) (*Example, error) {
+	to := &Example{}
 	var err error
 	if prehook, ok := interface{}(m).(ExampleWithBeforeToPB); ok {
-		if err = prehook.BeforeToPB(ctx, &to); err != nil {
+		if err = prehook.BeforeToPB(ctx, to); err != nil {
 			return to, err
 		}
 	}
@@ -73,8 +78,10 @@ func (m *ExampleORM) ToPB(ctx context.Context) (Example, error) {
 	if m.DeletedAt != nil {
 		to.DeletedAt = timestamppb.New(*m.DeletedAt)
 	}
+	to.Name = m.Name
+	to.Description = m.Description
 	if posthook, ok := interface{}(m).(ExampleWithAfterToPB); ok {
-		err = posthook.AfterToPB(ctx, &to)
+		err = posthook.AfterToPB(ctx, to)
 	}
 	return to, err
 }
@@ -102,6 +109,114 @@ type ExampleWithAfterToPB interface {
 	AfterToPB(context.Context, *Example) error
 }
 
+type ApiKeyORM struct {
+	CreatedAt  *time.Time
+	ExpiresAt  *time.Time
+	Id         uint64 `gorm:"primary_key;not null"`
+	KeyHash    string `gorm:"unique;not null"`
+	LastUsedAt *time.Time
+	Name       string
+	Prefix     string
+	RevokedAt  *time.Time
+	Scopes     string
+}
+
+// TableName overrides the default tablename generated by GORM
+func (ApiKeyORM) TableName() string {
+	return "api_keys"
+}
+
+// ToORM runs the BeforeToORM hook if present, converts the fields of this
+// object to ORM format, runs the AfterToORM hook, then returns the ORM object
+func (m *ApiKey) ToORM(ctx context.Context) (ApiKeyORM, error) {
+	to := ApiKeyORM{}
+	var err error
+	if prehook, ok := interface{}(m).(ApiKeyWithBeforeToORM); ok {
+		if err = prehook.BeforeToORM(ctx, &to); err != nil {
+			return to, err
+		}
+	}
+	to.Id = m.Id
+	to.Name = m.Name
+	to.Prefix = m.Prefix
+	to.Scopes = m.Scopes
+	if m.CreatedAt != nil {
+		t := m.CreatedAt.AsTime()
+		to.CreatedAt = &t
+	}
+	if m.ExpiresAt != nil {
+		t := m.ExpiresAt.AsTime()
+		to.ExpiresAt = &t
+	}
+	if m.LastUsedAt != nil {
+		t := m.LastUsedAt.AsTime()
+		to.LastUsedAt = &t
+	}
+	if m.RevokedAt != nil {
+		t := m.RevokedAt.AsTime()
+		to.RevokedAt = &t
+	}
+	if posthook, ok := interface{}(m).(ApiKeyWithAfterToORM); ok {
+		err = posthook.AfterToORM(ctx, &to)
+	}
+	return to, err
+}
+
+// ToPB runs the BeforeToPB hook if present, converts the fields of this
+// object to PB format, runs the AfterToPB hook, then returns the PB object
+func (m *ApiKeyORM) ToPB(ctx context.Context) (*ApiKey, error) {
+	to := &ApiKey{}
+	var err error
+	if prehook, ok := interface{}(m).(ApiKeyWithBeforeToPB); ok {
+		if err = prehook.BeforeToPB(ctx, to); err != nil {
+			return to, err
+		}
+	}
+	to.Id = m.Id
+	to.Name = m.Name
+	to.Prefix = m.Prefix
+	to.Scopes = m.Scopes
+	if m.CreatedAt != nil {
+		to.CreatedAt = timestamppb.New(*m.CreatedAt)
+	}
+	if m.ExpiresAt != nil {
+		to.ExpiresAt = timestamppb.New(*m.ExpiresAt)
+	}
+	if m.LastUsedAt != nil {
+		to.LastUsedAt = timestamppb.New(*m.LastUsedAt)
+	}
+	if m.RevokedAt != nil {
+		to.RevokedAt = timestamppb.New(*m.RevokedAt)
+	}
+	if posthook, ok := interface{}(m).(ApiKeyWithAfterToPB); ok {
+		err = posthook.AfterToPB(ctx, to)
+	}
+	return to, err
+}
+
+// The following are interfaces you can implement for special behavior during ORM/PB conversions
+// of type ApiKey the arg will be the target, the caller the one being converted from
+
+// ApiKeyBeforeToORM called before default ToORM code
+type ApiKeyWithBeforeToORM interface {
+	BeforeToORM(context.Context, *ApiKeyORM) error
+}
+
+// ApiKeyAfterToORM called after default ToORM code
+type ApiKeyWithAfterToORM interface {
+	AfterToORM(context.Context, *ApiKeyORM) error
+}
+
+// ApiKeyBeforeToPB called before default ToPB code
+type ApiKeyWithBeforeToPB interface {
+	BeforeToPB(context.Context, *ApiKey) error
+}
+
+// ApiKeyAfterToPB called after default ToPB code
+type ApiKeyWithAfterToPB interface {
+	AfterToPB(context.Context, *ApiKey) error
+}
+
 // DefaultCreateExample executes a basic gorm create call
 func DefaultCreateExample(ctx context.Context, in *Example, db *gorm.DB) (*Example, error) {
 	if in == nil {
@@ -125,7 +240,7 @@ func DefaultCreateExample(ctx context.Context, in *Example, db *gorm.DB) (*Examp
 		}
 	}
 	pbResponse, err := ormObj.ToPB(ctx)
-	return &pbResponse, err
+	return pbResponse, err
 }
 
 type ExampleORMWithBeforeCreate_ interface {
@@ -169,7 +284,7 @@ func DefaultReadExample(ctx context.Context, in *Example, db *gorm.DB) (*Example
 		}
 	}
 	pbResponse, err := ormResponse.ToPB(ctx)
-	return &pbResponse, err
+	return pbResponse, err
 }
 
 type ExampleORMWithBeforeReadApplyQuery interface {
@@ -263,7 +378,9 @@ func DefaultStrictUpdateExample(ctx context.Context, in *Example, db *gorm.DB) (
 		return nil, err
 	}
 	lockedRow := &ExampleORM{}
-	db.Model(&ormObj).Set("gorm:query_option", "FOR UPDATE").Where("id=?", ormObj.Id).First(lockedRow)
+	if err = db.Model(&ormObj).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=?", ormObj.Id).First(lockedRow).Error; err != nil {
+		return nil, err
+	}
 	if hook, ok := interface{}(&ormObj).(ExampleORMWithBeforeStrictUpdateCleanup); ok {
 		if db, err = hook.BeforeStrictUpdateCleanup(ctx, db); err != nil {
 			return nil, err
@@ -286,7 +403,7 @@ func DefaultStrictUpdateExample(ctx context.Context, in *Example, db *gorm.DB) (
 	if err != nil {
 		return nil, err
 	}
-	return &pbResponse, err
+	return pbResponse, err
 }
 
 type ExampleORMWithBeforeStrictUpdateCleanup interface {
@@ -304,9 +421,9 @@ func DefaultPatchExample(ctx context.Context, in *Example, updateMask *field_mas
 	if in == nil {
 		return nil, errors.NilArgumentError
 	}
-	var pbObj Example
+	pbObj := &Example{}
 	var err error
-	if hook, ok := interface{}(&pbObj).(ExampleWithBeforePatchRead); ok {
+	if hook, ok := interface{}(pbObj).(ExampleWithBeforePatchRead); ok {
 		if db, err = hook.BeforePatchRead(ctx, in, updateMask, db); err != nil {
 			return nil, err
 		}
@@ -315,21 +432,21 @@ func DefaultPatchExample(ctx context.Context, in *Example, updateMask *field_mas
 	if err != nil {
 		return nil, err
 	}
-	pbObj = *pbReadRes
-	if hook, ok := interface{}(&pbObj).(ExampleWithBeforePatchApplyFieldMask); ok {
+	pbObj = pbReadRes
+	if hook, ok := interface{}(pbObj).(ExampleWithBeforePatchApplyFieldMask); ok {
 		if db, err = hook.BeforePatchApplyFieldMask(ctx, in, updateMask, db); err != nil {
 			return nil, err
 		}
 	}
-	if _, err := DefaultApplyFieldMaskExample(ctx, &pbObj, in, updateMask, "", db); err != nil {
+	if _, err := DefaultApplyFieldMaskExample(ctx, pbObj, in, updateMask, "", db); err != nil {
 		return nil, err
 	}
-	if hook, ok := interface{}(&pbObj).(ExampleWithBeforePatchSave); ok {
+	if hook, ok := interface{}(pbObj).(ExampleWithBeforePatchSave); ok {
 		if db, err = hook.BeforePatchSave(ctx, in, updateMask, db); err != nil {
 			return nil, err
 		}
 	}
-	pbResponse, err := DefaultStrictUpdateExample(ctx, &pbObj, db)
+	pbResponse, err := DefaultStrictUpdateExample(ctx, pbObj, db)
 	if err != nil {
 		return nil, err
 	}
@@ -458,6 +575,14 @@ func DefaultApplyFieldMaskExample(ctx context.Context, patchee *Example, patcher
 			patchee.DeletedAt = patcher.DeletedAt
 			continue
 		}
+		if f == prefix+"Name" {
+			patchee.Name = patcher.Name
+			continue
+		}
+		if f == prefix+"Description" {
+			patchee.Description = patcher.Description
+			continue
+		}
 	}
 	if err != nil {
 		return nil, err
@@ -503,7 +628,7 @@ func DefaultListExample(ctx context.Context, db *gorm.DB) ([]*Example, error) {
 		if err != nil {
 			return nil, err
 		}
-		pbResponse = append(pbResponse, &temp)
+		pbResponse = append(pbResponse, temp)
 	}
 	return pbResponse, nil
 }
@@ -517,3 +642,457 @@ type ExampleORMWithBeforeListFind interface {
 type ExampleORMWithAfterListFind interface {
 	AfterListFind(context.Context, *gorm.DB, *[]ExampleORM) error
 }
+
+// DefaultCreateApiKey executes a basic gorm create call
+func DefaultCreateApiKey(ctx context.Context, in *ApiKey, db *gorm.DB) (*ApiKey, error) {
+	if in == nil {
+		return nil, errors.NilArgumentError
+	}
+	ormObj, err := in.ToORM(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithBeforeCreate_); ok {
+		if db, err = hook.BeforeCreate_(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	if err = db.Create(&ormObj).Error; err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithAfterCreate_); ok {
+		if err = hook.AfterCreate_(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	pbResponse, err := ormObj.ToPB(ctx)
+	return pbResponse, err
+}
+
+type ApiKeyORMWithBeforeCreate_ interface {
+	BeforeCreate_(context.Context, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyORMWithAfterCreate_ interface {
+	AfterCreate_(context.Context, *gorm.DB) error
+}
+
+func DefaultReadApiKey(ctx context.Context, in *ApiKey, db *gorm.DB) (*ApiKey, error) {
+	if in == nil {
+		return nil, errors.NilArgumentError
+	}
+	ormObj, err := in.ToORM(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ormObj.Id == 0 {
+		return nil, errors.EmptyIdError
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithBeforeReadApplyQuery); ok {
+		if db, err = hook.BeforeReadApplyQuery(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	if db, err = gorm1.ApplyFieldSelection(ctx, db, nil, &ApiKeyORM{}); err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithBeforeReadFind); ok {
+		if db, err = hook.BeforeReadFind(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	ormResponse := ApiKeyORM{}
+	if err = db.Where(&ormObj).First(&ormResponse).Error; err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(&ormResponse).(ApiKeyORMWithAfterReadFind); ok {
+		if err = hook.AfterReadFind(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	pbResponse, err := ormResponse.ToPB(ctx)
+	return pbResponse, err
+}
+
+type ApiKeyORMWithBeforeReadApplyQuery interface {
+	BeforeReadApplyQuery(context.Context, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyORMWithBeforeReadFind interface {
+	BeforeReadFind(context.Context, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyORMWithAfterReadFind interface {
+	AfterReadFind(context.Context, *gorm.DB) error
+}
+
+func DefaultDeleteApiKey(ctx context.Context, in *ApiKey, db *gorm.DB) error {
+	if in == nil {
+		return errors.NilArgumentError
+	}
+	ormObj, err := in.ToORM(ctx)
+	if err != nil {
+		return err
+	}
+	if ormObj.Id == 0 {
+		return errors.EmptyIdError
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithBeforeDelete_); ok {
+		if db, err = hook.BeforeDelete_(ctx, db); err != nil {
+			return err
+		}
+	}
+	err = db.Where(&ormObj).Delete(&ApiKeyORM{}).Error
+	if err != nil {
+		return err
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithAfterDelete_); ok {
+		err = hook.AfterDelete_(ctx, db)
+	}
+	return err
+}
+
+type ApiKeyORMWithBeforeDelete_ interface {
+	BeforeDelete_(context.Context, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyORMWithAfterDelete_ interface {
+	AfterDelete_(context.Context, *gorm.DB) error
+}
+
+func DefaultDeleteApiKeySet(ctx context.Context, in []*ApiKey, db *gorm.DB) error {
+	if in == nil {
+		return errors.NilArgumentError
+	}
+	var err error
+	keys := []uint64{}
+	for _, obj := range in {
+		ormObj, err := obj.ToORM(ctx)
+		if err != nil {
+			return err
+		}
+		if ormObj.Id == 0 {
+			return errors.EmptyIdError
+		}
+		keys = append(keys, ormObj.Id)
+	}
+	if hook, ok := (interface{}(&ApiKeyORM{})).(ApiKeyORMWithBeforeDeleteSet); ok {
+		if db, err = hook.BeforeDeleteSet(ctx, in, db); err != nil {
+			return err
+		}
+	}
+	err = db.Where("id in (?)", keys).Delete(&ApiKeyORM{}).Error
+	if err != nil {
+		return err
+	}
+	if hook, ok := (interface{}(&ApiKeyORM{})).(ApiKeyORMWithAfterDeleteSet); ok {
+		err = hook.AfterDeleteSet(ctx, in, db)
+	}
+	return err
+}
+
+type ApiKeyORMWithBeforeDeleteSet interface {
+	BeforeDeleteSet(context.Context, []*ApiKey, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyORMWithAfterDeleteSet interface {
+	AfterDeleteSet(context.Context, []*ApiKey, *gorm.DB) error
+}
+
+// DefaultStrictUpdateApiKey clears / replaces / appends first level 1:many children and then executes a gorm update call
+func DefaultStrictUpdateApiKey(ctx context.Context, in *ApiKey, db *gorm.DB) (*ApiKey, error) {
+	if in == nil {
+		return nil, fmt.Errorf("Nil argument to DefaultStrictUpdateApiKey")
+	}
+	ormObj, err := in.ToORM(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lockedRow := &ApiKeyORM{}
+	if err = db.Model(&ormObj).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=?", ormObj.Id).First(lockedRow).Error; err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithBeforeStrictUpdateCleanup); ok {
+		if db, err = hook.BeforeStrictUpdateCleanup(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithBeforeStrictUpdateSave); ok {
+		if db, err = hook.BeforeStrictUpdateSave(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	if err = db.Save(&ormObj).Error; err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithAfterStrictUpdateSave); ok {
+		if err = hook.AfterStrictUpdateSave(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	pbResponse, err := ormObj.ToPB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pbResponse, err
+}
+
+type ApiKeyORMWithBeforeStrictUpdateCleanup interface {
+	BeforeStrictUpdateCleanup(context.Context, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyORMWithBeforeStrictUpdateSave interface {
+	BeforeStrictUpdateSave(context.Context, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyORMWithAfterStrictUpdateSave interface {
+	AfterStrictUpdateSave(context.Context, *gorm.DB) error
+}
+
+// DefaultPatchApiKey executes a basic gorm update call with patch behavior
+func DefaultPatchApiKey(ctx context.Context, in *ApiKey, updateMask *field_mask.FieldMask, db *gorm.DB) (*ApiKey, error) {
+	if in == nil {
+		return nil, errors.NilArgumentError
+	}
+	pbObj := &ApiKey{}
+	var err error
+	if hook, ok := interface{}(pbObj).(ApiKeyWithBeforePatchRead); ok {
+		if db, err = hook.BeforePatchRead(ctx, in, updateMask, db); err != nil {
+			return nil, err
+		}
+	}
+	pbReadRes, err := DefaultReadApiKey(ctx, &ApiKey{Id: in.GetId()}, db)
+	if err != nil {
+		return nil, err
+	}
+	pbObj = pbReadRes
+	if hook, ok := interface{}(pbObj).(ApiKeyWithBeforePatchApplyFieldMask); ok {
+		if db, err = hook.BeforePatchApplyFieldMask(ctx, in, updateMask, db); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := DefaultApplyFieldMaskApiKey(ctx, pbObj, in, updateMask, "", db); err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(pbObj).(ApiKeyWithBeforePatchSave); ok {
+		if db, err = hook.BeforePatchSave(ctx, in, updateMask, db); err != nil {
+			return nil, err
+		}
+	}
+	pbResponse, err := DefaultStrictUpdateApiKey(ctx, pbObj, db)
+	if err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(pbResponse).(ApiKeyWithAfterPatchSave); ok {
+		if err = hook.AfterPatchSave(ctx, in, updateMask, db); err != nil {
+			return nil, err
+		}
+	}
+	return pbResponse, nil
+}
+
+type ApiKeyWithBeforePatchRead interface {
+	BeforePatchRead(context.Context, *ApiKey, *field_mask.FieldMask, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyWithBeforePatchApplyFieldMask interface {
+	BeforePatchApplyFieldMask(context.Context, *ApiKey, *field_mask.FieldMask, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyWithBeforePatchSave interface {
+	BeforePatchSave(context.Context, *ApiKey, *field_mask.FieldMask, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyWithAfterPatchSave interface {
+	AfterPatchSave(context.Context, *ApiKey, *field_mask.FieldMask, *gorm.DB) error
+}
+
+// DefaultPatchSetApiKey executes a bulk gorm update call with patch behavior
+func DefaultPatchSetApiKey(ctx context.Context, objects []*ApiKey, updateMasks []*field_mask.FieldMask, db *gorm.DB) ([]*ApiKey, error) {
+	if len(objects) != len(updateMasks) {
+		return nil, fmt.Errorf(errors.BadRepeatedFieldMaskTpl, len(updateMasks), len(objects))
+	}
+
+	results := make([]*ApiKey, 0, len(objects))
+	for i, patcher := range objects {
+		pbResponse, err := DefaultPatchApiKey(ctx, patcher, updateMasks[i], db)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, pbResponse)
+	}
+
+	return results, nil
+}
+
+// DefaultApplyFieldMaskApiKey patches an pbObject with patcher according to a field mask.
+func DefaultApplyFieldMaskApiKey(ctx context.Context, patchee *ApiKey, patcher *ApiKey, updateMask *field_mask.FieldMask, prefix string, db *gorm.DB) (*ApiKey, error) {
+	if patcher == nil {
+		return nil, nil
+	} else if patchee == nil {
+		return nil, errors.NilArgumentError
+	}
+	var err error
+	var updatedCreatedAt bool
+	var updatedExpiresAt bool
+	var updatedLastUsedAt bool
+	var updatedRevokedAt bool
+	for i, f := range updateMask.Paths {
+		if f == prefix+"Id" {
+			patchee.Id = patcher.Id
+			continue
+		}
+		if f == prefix+"Name" {
+			patchee.Name = patcher.Name
+			continue
+		}
+		if f == prefix+"Prefix" {
+			patchee.Prefix = patcher.Prefix
+			continue
+		}
+		if f == prefix+"Scopes" {
+			patchee.Scopes = patcher.Scopes
+			continue
+		}
+		if !updatedCreatedAt && strings.HasPrefix(f, prefix+"CreatedAt.") {
+			if patcher.CreatedAt == nil {
+				patchee.CreatedAt = nil
+				continue
+			}
+			if patchee.CreatedAt == nil {
+				patchee.CreatedAt = &timestamppb.Timestamp{}
+			}
+			childMask := &field_mask.FieldMask{}
+			for j := i; j < len(updateMask.Paths); j++ {
+				if trimPath := strings.TrimPrefix(updateMask.Paths[j], prefix+"CreatedAt."); trimPath != updateMask.Paths[j] {
+					childMask.Paths = append(childMask.Paths, trimPath)
+				}
+			}
+			if err := gorm1.MergeWithMask(patcher.CreatedAt, patchee.CreatedAt, childMask); err != nil {
+				return nil, nil
+			}
+		}
+		if f == prefix+"CreatedAt" {
+			updatedCreatedAt = true
+			patchee.CreatedAt = patcher.CreatedAt
+			continue
+		}
+		if !updatedExpiresAt && strings.HasPrefix(f, prefix+"ExpiresAt.") {
+			if patcher.ExpiresAt == nil {
+				patchee.ExpiresAt = nil
+				continue
+			}
+			if patchee.ExpiresAt == nil {
+				patchee.ExpiresAt = &timestamppb.Timestamp{}
+			}
+			childMask := &field_mask.FieldMask{}
+			for j := i; j < len(updateMask.Paths); j++ {
+				if trimPath := strings.TrimPrefix(updateMask.Paths[j], prefix+"ExpiresAt."); trimPath != updateMask.Paths[j] {
+					childMask.Paths = append(childMask.Paths, trimPath)
+				}
+			}
+			if err := gorm1.MergeWithMask(patcher.ExpiresAt, patchee.ExpiresAt, childMask); err != nil {
+				return nil, nil
+			}
+		}
+		if f == prefix+"ExpiresAt" {
+			updatedExpiresAt = true
+			patchee.ExpiresAt = patcher.ExpiresAt
+			continue
+		}
+		if !updatedLastUsedAt && strings.HasPrefix(f, prefix+"LastUsedAt.") {
+			if patcher.LastUsedAt == nil {
+				patchee.LastUsedAt = nil
+				continue
+			}
+			if patchee.LastUsedAt == nil {
+				patchee.LastUsedAt = &timestamppb.Timestamp{}
+			}
+			childMask := &field_mask.FieldMask{}
+			for j := i; j < len(updateMask.Paths); j++ {
+				if trimPath := strings.TrimPrefix(updateMask.Paths[j], prefix+"LastUsedAt."); trimPath != updateMask.Paths[j] {
+					childMask.Paths = append(childMask.Paths, trimPath)
+				}
+			}
+			if err := gorm1.MergeWithMask(patcher.LastUsedAt, patchee.LastUsedAt, childMask); err != nil {
+				return nil, nil
+			}
+		}
+		if f == prefix+"LastUsedAt" {
+			updatedLastUsedAt = true
+			patchee.LastUsedAt = patcher.LastUsedAt
+			continue
+		}
+		if !updatedRevokedAt && strings.HasPrefix(f, prefix+"RevokedAt.") {
+			if patcher.RevokedAt == nil {
+				patchee.RevokedAt = nil
+				continue
+			}
+			if patchee.RevokedAt == nil {
+				patchee.RevokedAt = &timestamppb.Timestamp{}
+			}
+			childMask := &field_mask.FieldMask{}
+			for j := i; j < len(updateMask.Paths); j++ {
+				if trimPath := strings.TrimPrefix(updateMask.Paths[j], prefix+"RevokedAt."); trimPath != updateMask.Paths[j] {
+					childMask.Paths = append(childMask.Paths, trimPath)
+				}
+			}
+			if err := gorm1.MergeWithMask(patcher.RevokedAt, patchee.RevokedAt, childMask); err != nil {
+				return nil, nil
+			}
+		}
+		if f == prefix+"RevokedAt" {
+			updatedRevokedAt = true
+			patchee.RevokedAt = patcher.RevokedAt
+			continue
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return patchee, nil
+}
+
+// DefaultListApiKey executes a gorm list call
+func DefaultListApiKey(ctx context.Context, db *gorm.DB) ([]*ApiKey, error) {
+	in := ApiKey{}
+	ormObj, err := in.ToORM(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithBeforeListApplyQuery); ok {
+		if db, err = hook.BeforeListApplyQuery(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	db, err = gorm1.ApplyCollectionOperators(ctx, db, &ApiKeyORM{}, &ApiKey{}, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithBeforeListFind); ok {
+		if db, err = hook.BeforeListFind(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+	db = db.Where(&ormObj)
+	db = db.Order("id")
+	ormResponse := []ApiKeyORM{}
+	if err := db.Find(&ormResponse).Error; err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(&ormObj).(ApiKeyORMWithAfterListFind); ok {
+		if err = hook.AfterListFind(ctx, db, &ormResponse); err != nil {
+			return nil, err
+		}
+	}
+	pbResponse := []*ApiKey{}
+	for _, responseEntry := range ormResponse {
+		temp, err := responseEntry.ToPB(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pbResponse = append(pbResponse, temp)
+	}
+	return pbResponse, nil
+}
+
+type ApiKeyORMWithBeforeListApplyQuery interface {
+	BeforeListApplyQuery(context.Context, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyORMWithBeforeListFind interface {
+	BeforeListFind(context.Context, *gorm.DB) (*gorm.DB, error)
+}
+type ApiKeyORMWithAfterListFind interface {
+	AfterListFind(context.Context, *gorm.DB, *[]ApiKeyORM) error
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata keys reporting the bucket of the caller in the response header.
+const (
+	LimitKey      = "x-ratelimit-limit"
+	RemainingKey  = "x-ratelimit-remaining"
+	ResetKey      = "x-ratelimit-reset"
+	RetryAfterKey = "retry-after"
+)
+
+// Headers maps the metadata keys to the HTTP headers the gateway answers
+// with.
+var Headers = map[string]string{
+	LimitKey:      "X-RateLimit-Limit",
+	RemainingKey:  "X-RateLimit-Remaining",
+	ResetKey:      "X-RateLimit-Reset",
+	RetryAfterKey: "Retry-After",
+}
+
+// Seconds formats d as whole seconds, rounded up, as Retry-After expects.
+func Seconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+// Proxies are the proxies trusted to append the address of their client to
+// X-Forwarded-For.
+type Proxies []netip.Prefix
+
+// ParseProxies parses IP addresses and CIDR ranges.
+func ParseProxies(specs []string) (Proxies, error) {
+	proxies := make(Proxies, 0, len(specs))
+	for _, spec := range specs {
+		if addr, err := netip.ParseAddr(spec); err == nil {
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q, want an IP address or a CIDR range", spec)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+// trusts reports whether host is a trusted proxy. Loopback and an unknown
+// peer, such as the in-process gateway, are always trusted.
+func (p Proxies) trusts(host string) bool {
+	if host == "" {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() {
+		return true
+	}
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the IP address of a client connected from remoteAddr
+// (host:port). X-Forwarded-For is only used when the connection comes from a
+// trusted proxy. Its hops are then read from the right, skipping the ones
+// appended by trusted proxies: the first other hop is the address the
+// outermost trusted proxy saw, everything left of it was sent by the client.
+func ClientIP(remoteAddr string, forwarded []string, proxies Proxies) string {
+	host := stripPort(remoteAddr)
+	if !proxies.trusts(host) {
+		return host
+	}
+
+	var hops []string
+	for _, value := range forwarded {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, stripPort(hop))
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !proxies.trusts(hops[i]) {
+			return hops[i]
+		}
+	}
+	if len(hops) > 0 {
+		// Every hop is a trusted proxy, the first one is the closest to the
+		// client
+		return hops[0]
+	}
+
+	return host
+}
+
+// stripPort returns the host of an address, with or without a port.
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
@@ -0,0 +1,117 @@
+// Package ratelimit implements per-client token buckets.
+//
+// Every caller gets a bucket per method holding up to Burst tokens, refilled
+// at Rate tokens per second. A request takes one token and is rejected when
+// the bucket is empty. Buckets of idle callers are dropped once they would
+// be full again.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped.
+const sweepInterval = time.Minute
+
+// Rule is the token bucket of a caller.
+type Rule struct {
+	// Rate is the number of tokens added per second.
+	Rate float64
+	// Burst is the size of the bucket.
+	Burst int
+}
+
+// Disabled reports whether the rule lets every request through.
+func (r Rule) Disabled() bool {
+	return r.Rate <= 0 || r.Burst <= 0
+}
+
+// Result is the outcome of a request against a bucket.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket.
+	Limit int
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// RetryAfter is how long until a token is available, when not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+// Limiter holds the buckets of every caller.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New returns an empty Limiter.
+func New() *Limiter {
+	return &Limiter{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key, created full on first use.
+func (l *Limiter) Allow(key string, rule Rule) Result {
+	if rule.Disabled() {
+		return Result{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rule.Rate)
+	b.last = now
+
+	result := Result{Limit: rule.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rule.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(rule.Burst) - b.tokens) / rule.Rate)
+	b.full = now.Add(result.Reset)
+
+	return result
+}
+
+// sweep drops the buckets that have refilled completely since their last
+// use, which behave the same as a new bucket.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
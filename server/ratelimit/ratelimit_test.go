@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := New()
+	l.now = func() time.Time { return now }
+	rule := Rule{Rate: 2, Burst: 3}
+
+	steps := []struct {
+		name       string
+		advance    time.Duration
+		key        string
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{name: "new bucket is full", key: "a", allowed: true, remaining: 2, reset: 500 * time.Millisecond},
+		{name: "second token", key: "a", allowed: true, remaining: 1, reset: time.Second},
+		{name: "last token", key: "a", allowed: true, remaining: 0, reset: 1500 * time.Millisecond},
+		{name: "empty bucket", key: "a", allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond, reset: 1500 * time.Millisecond},
+		{name: "other key has its own bucket", key: "b", allowed: true, remaining: 2, reset: 500 * time.Millisecond},
+		{name: "half a token refilled", advance: 250 * time.Millisecond, key: "a", allowed: false, remaining: 0, retryAfter: 250 * time.Millisecond, reset: 1250 * time.Millisecond},
+		{name: "one token refilled", advance: 250 * time.Millisecond, key: "a", allowed: true, remaining: 0, reset: 1500 * time.Millisecond},
+		{name: "refill is capped at burst", advance: time.Hour, key: "a", allowed: true, remaining: 2, reset: 500 * time.Millisecond},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		got := l.Allow(step.key, rule)
+		if got.Allowed != step.allowed || got.Limit != rule.Burst || got.Remaining != step.remaining ||
+			got.RetryAfter != step.retryAfter || got.Reset != step.reset {
+			t.Errorf("%s: Allow = %+v, want allowed %v, remaining %d, retry after %v, reset %v",
+				step.name, got, step.allowed, step.remaining, step.retryAfter, step.reset)
+		}
+	}
+}
+
+func TestLimiterDisabledRule(t *testing.T) {
+	l := New()
+	for _, rule := range []Rule{{Rate: 0, Burst: 10}, {Rate: 10, Burst: 0}} {
+		for i := 0; i < 100; i++ {
+			if got := l.Allow("a", rule); !got.Allowed {
+				t.Fatalf("Allow(%+v) = %+v, want allowed", rule, got)
+			}
+		}
+	}
+	if len(l.buckets) != 0 {
+		t.Errorf("disabled rules created %d buckets", len(l.buckets))
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := New()
+	l.now = func() time.Time { return now }
+	l.Allow("idle", Rule{Rate: 1, Burst: 1})
+	l.Allow("slow", Rule{Rate: 0.001, Burst: 1})
+
+	now = now.Add(sweepInterval)
+	l.Allow("new", Rule{Rate: 1, Burst: 1})
+	if _, ok := l.buckets["idle"]; ok {
+		t.Errorf("refilled bucket kept after a sweep")
+	}
+	if _, ok := l.buckets["slow"]; !ok {
+		t.Errorf("bucket still refilling dropped by a sweep")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseProxies([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("ParseProxies: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		proxies    Proxies
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "untrusted peer ignores the header", remoteAddr: "203.0.113.7:5000", forwarded: []string{"198.51.100.1"}, proxies: proxies, want: "203.0.113.7"},
+		{name: "gateway on loopback", remoteAddr: "127.0.0.1:6000", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "gateway in-process", remoteAddr: "", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "loopback without the header", remoteAddr: "[::1]:6000", want: "::1"},
+		{name: "spoofed entry before the client", remoteAddr: "127.0.0.1:6000", forwarded: []string{"1.2.3.4, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "one proxy", remoteAddr: "10.1.2.3:443", forwarded: []string{"1.2.3.4, 203.0.113.7"}, proxies: proxies, want: "203.0.113.7"},
+		{name: "chain of proxies", remoteAddr: "10.1.2.3:443", forwarded: []string{"1.2.3.4, 203.0.113.7", "192.0.2.1, 10.9.9.9"}, proxies: proxies, want: "203.0.113.7"},
+		{name: "proxy without configured proxies", remoteAddr: "10.1.2.3:443", forwarded: []string{"203.0.113.7"}, want: "10.1.2.3"},
+		{name: "untrusted hop in the chain", remoteAddr: "10.1.2.3:443", forwarded: []string{"203.0.113.7, 198.51.100.1"}, proxies: proxies, want: "198.51.100.1"},
+		{name: "only trusted hops", remoteAddr: "10.1.2.3:443", forwarded: []string{"10.0.0.1, 10.0.0.2"}, proxies: proxies, want: "10.0.0.1"},
+		{name: "empty header", remoteAddr: "10.1.2.3:443", forwarded: []string{" , "}, proxies: proxies, want: "10.1.2.3"},
+		{name: "hop with a port", remoteAddr: "10.1.2.3:443", forwarded: []string{"203.0.113.7:1234"}, proxies: proxies, want: "203.0.113.7"},
+		{name: "IPv6 proxy", remoteAddr: "[2001:db8::1]:443", forwarded: []string{"2001:db8:1::5, 2001:db8::2"}, proxies: proxies, want: "2001:db8:1::5"},
+		{name: "IPv4-mapped proxy", remoteAddr: "[::ffff:10.1.2.3]:443", forwarded: []string{"203.0.113.7"}, proxies: proxies, want: "203.0.113.7"},
+		{name: "remote address without a port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		if got := ClientIP(tt.remoteAddr, tt.forwarded, tt.proxies); got != tt.want {
+			t.Errorf("%s: ClientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseProxies(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "10.0.0.0/8", want: "10.0.0.0/8"},
+		{spec: "10.1.2.3/8", want: "10.0.0.0/8"},
+		{spec: "192.0.2.1", want: "192.0.2.1/32"},
+		{spec: "::ffff:192.0.2.1", want: "192.0.2.1/32"},
+		{spec: "2001:db8::/32", want: "2001:db8::/32"},
+		{spec: "2001:db8::1", want: "2001:db8::1/128"},
+		{spec: "10.0.0.0/33", wantErr: true},
+		{spec: "proxy.internal", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseProxies([]string{tt.spec})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseProxies(%q) = %v, want an error", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil || len(got) != 1 || got[0].String() != tt.want {
+			t.Errorf("ParseProxies(%q) = %v, %v, want %s", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	def := Rule{Rate: 10, Burst: 20}
+	rules := NewRules(def, map[string]Rule{
+		"GetExample": {Rate: 1, Burst: 2},
+		"/responsetimesimulation.service.ApiService/DeleteExample": {Rate: 0, Burst: 0},
+	})
+
+	tests := []struct {
+		method string
+		want   Rule
+	}{
+		{method: "/responsetimesimulation.service.ApiService/GetExample", want: Rule{Rate: 1, Burst: 2}},
+		{method: "/responsetimesimulation.service.ApiService/DeleteExample", want: Rule{}},
+		{method: "/responsetimesimulation.service.ApiService/CreateApiKey", want: Rule{Rate: 0.1, Burst: 5}},
+		{method: "/responsetimesimulation.service.ApiService/ListExamples", want: def},
+		{method: "/unknown.Service/Method", want: def},
+	}
+	for _, tt := range tests {
+		// The second lookup is served from the cache
+		for i := 0; i < 2; i++ {
+			if got := rules.For(tt.method); got != tt.want {
+				t.Errorf("For(%s) = %+v, want %+v", tt.method, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+// Rules picks the rule of each method: an override from the configuration,
+// else the (rate_limit) option of the method, else the default.
+type Rules struct {
+	def       Rule
+	overrides map[string]Rule
+	cache     sync.Map
+}
+
+// NewRules returns the rules of def with the overrides, keyed by either the
+// method name (CreateExample) or the full gRPC method
+// (/package.Service/Method).
+func NewRules(def Rule, overrides map[string]Rule) *Rules {
+	r := &Rules{def: def, overrides: map[string]Rule{}}
+	for method, rule := range overrides {
+		r.overrides[method] = rule
+	}
+
+	return r
+}
+
+// For returns the rule of fullMethod, in the "/package.Service/Method" form
+// of grpc.UnaryServerInfo.
+func (r *Rules) For(fullMethod string) Rule {
+	if rule, ok := r.cache.Load(fullMethod); ok {
+		return rule.(Rule)
+	}
+
+	rule := r.lookup(fullMethod)
+	r.cache.Store(fullMethod, rule)
+
+	return rule
+}
+
+func (r *Rules) lookup(fullMethod string) Rule {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if rule, ok := r.overrides[fullMethod]; ok {
+		return rule
+	}
+	if rule, ok := r.overrides[method]; ok {
+		return rule
+	}
+
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service + "." + method))
+	if err != nil {
+		return r.def
+	}
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok || !proto.HasExtension(md.Options(), pb.E_RateLimit) {
+		return r.def
+	}
+	opt := proto.GetExtension(md.Options(), pb.E_RateLimit).(*pb.MethodRateLimit)
+
+	return Rule{Rate: opt.GetRps(), Burst: int(opt.GetBurst())}
+}
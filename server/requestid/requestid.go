@@ -0,0 +1,72 @@
+// Package requestid carries the correlation identifier of a request across
+// the HTTP gateway, gRPC and logs.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/logging"
+)
+
+const (
+	// Header is the HTTP header the request ID is read from and echoed in.
+	Header = "X-Request-Id"
+	// MetadataKey is the gRPC metadata key the request ID travels in.
+	MetadataKey = "x-request-id"
+
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// New generates a random request ID formatted as a UUID v4.
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Valid reports whether a request ID received from a client can be reused:
+// non-empty, at most 128 characters and printable ASCII only.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Ensure returns id when it is valid, otherwise a newly generated one.
+func Ensure(id string) string {
+	if Valid(id) {
+		return id
+	}
+
+	return New()
+}
+
+// NewContext returns a copy of ctx carrying id, which is also added to the
+// log fields of the context.
+func NewContext(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, contextKey{}, id)
+	return logging.With(ctx, slog.String("request_id", id))
+}
+
+// FromContext returns the request ID carried by ctx, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,135 @@
+// Package shutdown coordinates the orderly stop of the service. Components
+// register hooks in a phase; on shutdown the service is first marked as not
+// ready, then the phases run one after another while the hooks of a phase run
+// concurrently.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phase orders shutdown hooks. Lower phases run first.
+type Phase int
+
+const (
+	// PhaseNotReady tells load balancers and health clients to stop sending
+	// new work.
+	PhaseNotReady Phase = 0
+	// PhaseServers stops accepting work and drains in-flight requests.
+	PhaseServers Phase = 100
+	// PhaseWorkers stops background jobs that may still use resources.
+	PhaseWorkers Phase = 200
+	// PhaseResources closes connections such as the database.
+	PhaseResources Phase = 300
+)
+
+// Hook stops a component. It should return once the component is stopped or
+// ctx is done.
+type Hook func(ctx context.Context) error
+
+type hook struct {
+	name  string
+	phase Phase
+	fn    Hook
+}
+
+// Registry holds the shutdown hooks of the service.
+type Registry struct {
+	mu           sync.Mutex
+	hooks        []hook
+	shuttingDown atomic.Bool
+	once         sync.Once
+	err          error
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a hook to run in the given phase.
+func (r *Registry) Register(name string, phase Phase, fn Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, hook{name: name, phase: phase, fn: fn})
+}
+
+// ShuttingDown reports whether Shutdown has been called. Readiness checks
+// use it to take the service out of rotation.
+func (r *Registry) ShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+// Shutdown marks the service as not ready and runs every hook, giving all of
+// them together at most timeout. Errors are logged and joined; a failing
+// hook does not prevent the following ones from running. Only the first call
+// has any effect.
+func (r *Registry) Shutdown(timeout time.Duration) error {
+	r.once.Do(func() {
+		r.shuttingDown.Store(true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		r.err = r.run(ctx)
+	})
+
+	return r.err
+}
+
+func (r *Registry) run(ctx context.Context) error {
+	r.mu.Lock()
+	hooks := append([]hook(nil), r.hooks...)
+	r.mu.Unlock()
+
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].phase < hooks[j].phase
+	})
+
+	var errs []error
+	for start := 0; start < len(hooks); {
+		end := start
+		for end < len(hooks) && hooks[end].phase == hooks[start].phase {
+			end++
+		}
+		errs = append(errs, runPhase(ctx, hooks[start:end])...)
+		start = end
+	}
+
+	return errors.Join(errs...)
+}
+
+func runPhase(ctx context.Context, hooks []hook) []error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, h := range hooks {
+		wg.Add(1)
+		go func(h hook) {
+			defer wg.Done()
+
+			log.Printf("Shutdown %s ... ", h.name)
+			if err := h.fn(ctx); err != nil {
+				log.Printf("Shutdown %s failed: %v", h.name, err)
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+				mu.Unlock()
+				return
+			}
+			log.Printf("Shutdown %s success", h.name)
+		}(h)
+	}
+	wg.Wait()
+
+	return errs
+}
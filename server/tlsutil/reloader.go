@@ -0,0 +1,206 @@
+// Package tlsutil builds the TLS configurations of the listeners and of the
+// gateway's dial from certificate files, and reloads them when the files
+// are rotated on disk so no restart is needed.
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader holds a certificate with its key and an optional CA bundle, as
+// read from files, and swaps them whenever Watch sees the files change.
+// The configurations it returns always use the latest successfully loaded
+// files.
+type Reloader struct {
+	certFile, keyFile, caFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime map[string]time.Time
+}
+
+// NewReloader loads the files. certFile and keyFile may be empty for a
+// client without a certificate, caFile may be empty to rely on the system
+// roots (client) or to skip client certificate verification (server).
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// ServerConfig returns the configuration of a TLS listener. With
+// verifyClient, clients must present a certificate signed by the CA bundle.
+func (r *Reloader) ServerConfig(verifyClient bool) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2", "http/1.1"},
+			}
+			if verifyClient {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = pool
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig returns the configuration to dial serverName, presenting the
+// certificate when the server asks for one.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+	}
+	if r.caFile == "" {
+		return cfg
+	}
+
+	// The CA bundle can change after the config has been handed to the
+	// dialer, so the server certificate is verified against the current
+	// bundle here instead of a fixed RootCAs.
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("tls: server presented no certificate")
+		}
+		_, pool := r.current()
+		opts := x509.VerifyOptions{
+			DNSName:       serverName,
+			Roots:         pool,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+
+	return cfg
+}
+
+// Watch checks the files every interval and reloads them when one has
+// changed, until ctx is done. A failed reload keeps the previous files.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !r.changed() {
+			continue
+		}
+		if err := r.load(); err != nil {
+			slog.Error("Failed to reload TLS files, keeping the previous ones",
+				slog.String("cert", r.certFile),
+				slog.String("ca", r.caFile),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		slog.Info("TLS files reloaded", slog.String("cert", r.certFile), slog.String("ca", r.caFile))
+	}
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, r.pool
+}
+
+func (r *Reloader) files() []string {
+	var files []string
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+
+	return files
+}
+
+func (r *Reloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			// Files are often replaced in several steps, retry next tick
+			continue
+		}
+		if !info.ModTime().Equal(r.modTime[f]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Reloader) load() error {
+	modTime := map[string]time.Time{}
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTime[f] = info.ModTime()
+	}
+
+	var cert *tls.Certificate
+	if r.certFile != "" || r.keyFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("load certificate %s: %w", r.certFile, err)
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA bundle %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool, r.modTime = cert, pool, modTime
+	r.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataCarrier adapts gRPC metadata to the OpenTelemetry propagators, so
+// the W3C traceparent header travels as gRPC metadata.
+type MetadataCarrier metadata.MD
+
+func (c MetadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (c MetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Extract returns ctx with the remote span context found in md.
+func Extract(ctx context.Context, md metadata.MD) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, MetadataCarrier(md))
+}
+
+// ExtractHTTP returns ctx with the remote span context found in header.
+func ExtractHTTP(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject returns the metadata propagating the span context of ctx.
+func Inject(ctx context.Context) metadata.MD {
+	md := metadata.MD{}
+	otel.GetTextMapPropagator().Inject(ctx, MetadataCarrier(md))
+
+	return md
+}
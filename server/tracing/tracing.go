@@ -0,0 +1,147 @@
+// Package tracing sets up OpenTelemetry tracing: the global tracer provider,
+// the W3C trace context propagator and the span exporter selected by the
+// configuration.
+//
+// Exporters are looked up by name in a registry. "none", "stdout" and "file"
+// are built in; others (OTLP, Jaeger, ...) are plugged in with
+// RegisterExporter before Setup is called.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/config"
+)
+
+// instrumentationName identifies the spans created by this service.
+const instrumentationName = "github.com/sandisuryadi36/micro-svc-template/server/tracing"
+
+// ExporterFactory builds a span exporter from the tracing configuration. A
+// nil exporter with a nil error disables exporting.
+type ExporterFactory func(cfg config.TracingConfig) (sdktrace.SpanExporter, error)
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = map[string]ExporterFactory{
+		"none":   newNoneExporter,
+		"stdout": newStdoutExporter,
+		"file":   newFileExporter,
+	}
+)
+
+// RegisterExporter makes an exporter available under name for
+// TRACING_EXPORTER. Registering a name twice replaces the previous factory.
+func RegisterExporter(name string, factory ExporterFactory) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+
+	exporters[strings.ToLower(name)] = factory
+}
+
+// Tracer returns the tracer used by the service instrumentation. It follows
+// the provider installed by Setup even when obtained before.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the global tracer provider and propagator. The returned
+// function flushes pending spans and releases the exporter; it is meant to
+// be registered as a shutdown hook.
+func Setup(cfg config.TracingConfig) (func(context.Context) error, error) {
+	exportersMu.RLock()
+	factory, ok := exporters[strings.ToLower(cfg.Exporter)]
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	exportersMu.RUnlock()
+	if !ok {
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown tracing exporter %q, available: %s", cfg.Exporter, strings.Join(names, ", "))
+	}
+
+	exporter, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("tracing exporter %s: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	// Spans are still created without an exporter so trace IDs propagate
+	// and show up in logs.
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+func newNoneExporter(config.TracingConfig) (sdktrace.SpanExporter, error) {
+	return nil, nil
+}
+
+// newStdoutExporter writes spans as JSON to stdout, handy during local
+// development without a collector.
+func newStdoutExporter(config.TracingConfig) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+}
+
+// newFileExporter appends spans as JSON lines to TRACING_FILE.
+func newFileExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &fileExporter{SpanExporter: exporter, file: f}, nil
+}
+
+// fileExporter closes its file once the exporter is shut down.
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if cerr := e.file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,222 @@
+// Package validate checks requests against the (rules) field options
+// declared in proto/validate.proto.
+//
+// Rules are read from the descriptors once per field and applied
+// recursively to every set message field. A request carrying a
+// google.protobuf.FieldMask named update_mask is a partial update: its
+// other message fields are only checked on the paths of the mask.
+package validate
+
+import (
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/apperr"
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+// updateMaskField is the field name that marks a partial update.
+const updateMaskField = "update_mask"
+
+// fieldRules caches the rules of each field by full name, nil when the
+// field has none.
+var fieldRules sync.Map
+
+// patterns caches the compiled pattern rules, nil when invalid.
+var patterns sync.Map
+
+// Message returns the violations of the rules of msg and its nested
+// messages, with fields named by their path (data.name, items[1].id).
+func Message(msg proto.Message) []*apperr.Violation {
+	var violations []*apperr.Violation
+	check(msg.ProtoReflect(), "", nil, &violations)
+
+	return violations
+}
+
+// check validates the fields of m. A non-nil mask limits it to the named
+// fields.
+func check(m protoreflect.Message, prefix string, mask map[string]bool, violations *[]*apperr.Violation) {
+	fields := m.Descriptor().Fields()
+	childMask := updateMask(m)
+
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if mask != nil && !mask[string(fd.Name())] {
+			continue
+		}
+		path := string(fd.Name())
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		rules := rulesOf(fd)
+		add := func(field, format string, args ...interface{}) {
+			*violations = append(*violations, apperr.Field(field, fmt.Sprintf(format, args...)))
+		}
+
+		switch {
+		case fd.IsList():
+			list := m.Get(fd).List()
+			if rules != nil {
+				checkLength(rules, list.Len(), "items", path, add)
+				if rules.GetRequired() && list.Len() == 0 {
+					add(path, "is required")
+				}
+			}
+			for j := 0; j < list.Len(); j++ {
+				item := fmt.Sprintf("%s[%d]", path, j)
+				if fd.Message() != nil {
+					check(list.Get(j).Message(), item, nil, violations)
+				} else if rules != nil {
+					checkScalar(fd, list.Get(j), rules, item, add)
+				}
+			}
+		case fd.IsMap():
+			if rules.GetRequired() && m.Get(fd).Map().Len() == 0 {
+				add(path, "is required")
+			}
+		case fd.Message() != nil:
+			if !m.Has(fd) {
+				if rules.GetRequired() {
+					add(path, "is required")
+				}
+				continue
+			}
+			var sub map[string]bool
+			if childMask != nil && fd.Name() != updateMaskField {
+				sub = childMask
+			}
+			check(m.Get(fd).Message(), path, sub, violations)
+		case rules != nil:
+			if rules.GetRequired() && !m.Has(fd) {
+				add(path, "is required")
+				continue
+			}
+			checkScalar(fd, m.Get(fd), rules, path, add)
+		}
+	}
+}
+
+func checkScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value, rules *pb.FieldRules, path string, add func(field, format string, args ...interface{})) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		s := v.String()
+		checkLength(rules, utf8.RuneCountInString(s), "characters", path, add)
+		if s == "" {
+			return
+		}
+		if rules.GetPattern() != "" {
+			if re := pattern(rules.GetPattern()); re != nil && !re.MatchString(s) {
+				add(path, "must match %s", rules.GetPattern())
+			}
+		}
+		if rules.GetEmail() {
+			if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+				add(path, "must be an email address")
+			}
+		}
+		if len(rules.GetIn()) > 0 && !contains(rules.GetIn(), s) {
+			add(path, "must be one of %s", strings.Join(rules.GetIn(), ", "))
+		}
+	case protoreflect.BytesKind:
+		checkLength(rules, len(v.Bytes()), "bytes", path, add)
+	case protoreflect.EnumKind:
+		if rules.GetDefinedOnly() && fd.Enum().Values().ByNumber(v.Enum()) == nil {
+			add(path, "must be a defined value")
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		checkRange(rules, float64(v.Int()), path, add)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		checkRange(rules, float64(v.Uint()), path, add)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		checkRange(rules, v.Float(), path, add)
+	}
+}
+
+func checkLength(rules *pb.FieldRules, n int, unit, path string, add func(field, format string, args ...interface{})) {
+	if rules.MinLen != nil && n < int(rules.GetMinLen()) {
+		add(path, "must have at least %d %s", rules.GetMinLen(), unit)
+	}
+	if rules.MaxLen != nil && n > int(rules.GetMaxLen()) {
+		add(path, "must have at most %d %s", rules.GetMaxLen(), unit)
+	}
+}
+
+func checkRange(rules *pb.FieldRules, f float64, path string, add func(field, format string, args ...interface{})) {
+	if rules.Min != nil && f < rules.GetMin() {
+		add(path, "must be at least %g", rules.GetMin())
+	}
+	if rules.Max != nil && f > rules.GetMax() {
+		add(path, "must be at most %g", rules.GetMax())
+	}
+}
+
+// updateMask returns the top-level paths of the update_mask of m, or nil
+// when m is not a partial update.
+func updateMask(m protoreflect.Message) map[string]bool {
+	fd := m.Descriptor().Fields().ByName(updateMaskField)
+	if fd == nil || fd.Message() == nil || fd.Message().FullName() != "google.protobuf.FieldMask" || !m.Has(fd) {
+		return nil
+	}
+	fm, ok := m.Get(fd).Message().Interface().(*fieldmaskpb.FieldMask)
+	if !ok {
+		return nil
+	}
+
+	mask := map[string]bool{}
+	for _, path := range fm.GetPaths() {
+		name, _, _ := strings.Cut(path, ".")
+		mask[name] = true
+	}
+
+	return mask
+}
+
+func rulesOf(fd protoreflect.FieldDescriptor) *pb.FieldRules {
+	if rules, ok := fieldRules.Load(fd.FullName()); ok {
+		return rules.(*pb.FieldRules)
+	}
+
+	var rules *pb.FieldRules
+	if proto.HasExtension(fd.Options(), pb.E_Rules) {
+		rules = proto.GetExtension(fd.Options(), pb.E_Rules).(*pb.FieldRules)
+	}
+	fieldRules.Store(fd.FullName(), rules)
+
+	return rules
+}
+
+func pattern(expr string) *regexp.Regexp {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		slog.Error("Invalid pattern rule, ignored", slog.String("pattern", expr), slog.String("error", err.Error()))
+		re = nil
+	}
+	patterns.Store(expr, re)
+
+	return re
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
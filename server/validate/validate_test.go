@@ -0,0 +1,72 @@
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/sandisuryadi36/micro-svc-template/server/pb"
+)
+
+func TestMessage(t *testing.T) {
+	long := strings.Repeat("a", 256)
+	mask := func(paths ...string) *fieldmaskpb.FieldMask {
+		return &fieldmaskpb.FieldMask{Paths: paths}
+	}
+
+	tests := []struct {
+		name string
+		msg  proto.Message
+		want []string
+	}{
+		{name: "valid", msg: &pb.CreateExampleRequest{Data: &pb.Example{Name: "a"}}},
+		{name: "missing message", msg: &pb.CreateExampleRequest{}, want: []string{"data: is required"}},
+		{name: "missing nested field", msg: &pb.CreateExampleRequest{Data: &pb.Example{}}, want: []string{"data.name: is required"}},
+		{
+			name: "every nested violation",
+			msg:  &pb.CreateExampleRequest{Data: &pb.Example{Name: long, Description: strings.Repeat("a", 2001)}},
+			want: []string{"data.name: must have at most 255 characters", "data.description: must have at most 2000 characters"},
+		},
+		{name: "multibyte characters counted once", msg: &pb.CreateExampleRequest{Data: &pb.Example{Name: strings.Repeat("é", 255)}}},
+		{name: "minimum", msg: &pb.GetExampleRequest{}, want: []string{"id: must be at least 1"}},
+		{name: "pattern", msg: &pb.CreateApiKeyRequest{Name: "ci", Scopes: "read;write"}, want: []string{"scopes: must match ^[A-Za-z0-9:_.* -]*$"}},
+		{name: "empty value skips the pattern", msg: &pb.CreateApiKeyRequest{Name: "ci"}},
+		{name: "full update", msg: &pb.UpdateExampleRequest{Id: 1, Data: &pb.Example{}}, want: []string{"data.name: is required"}},
+		{
+			name: "patch outside the mask",
+			msg:  &pb.PatchExampleRequest{Id: 1, Data: &pb.Example{Description: "b"}, UpdateMask: mask("description")},
+		},
+		{
+			name: "patch on the mask",
+			msg:  &pb.PatchExampleRequest{Id: 1, Data: &pb.Example{Description: strings.Repeat("a", 2001)}, UpdateMask: mask("name", "description")},
+			want: []string{"data.name: is required", "data.description: must have at most 2000 characters"},
+		},
+		{
+			name: "patch checks the fields of the request",
+			msg:  &pb.PatchExampleRequest{Data: &pb.Example{Name: "a"}, UpdateMask: mask("name")},
+			want: []string{"id: must be at least 1"},
+		},
+		{
+			name: "patch requires the data",
+			msg:  &pb.PatchExampleRequest{Id: 1, UpdateMask: mask("name")},
+			want: []string{"data: is required"},
+		},
+		{
+			name: "patch without a mask",
+			msg:  &pb.PatchExampleRequest{Id: 1, Data: &pb.Example{Name: long}},
+			want: []string{"data.name: must have at most 255 characters"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, v := range Message(tt.msg) {
+			got = append(got, v.GetField()+": "+v.GetDescription())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Message = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}